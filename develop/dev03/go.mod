@@ -0,0 +1,9 @@
+module minisort
+
+go 1.23.3
+
+require gosort v0.0.0
+
+require golang.org/x/text v0.21.0 // indirect
+
+replace gosort => ../dev04
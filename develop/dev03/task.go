@@ -25,6 +25,114 @@ package main
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gosort/sortlib"
+)
+
+// config — разобранные аргументы командной строки
+type config struct {
+	options sortlib.Options
+	check   bool     // -c
+	files   []string // входные файлы; "-" — стандартный ввод
+}
+
 func main() {
+	cfg, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if cfg.check {
+		os.Exit(check(cfg))
+	}
+
+	in := sortlib.NewInputReader(cfg.files, cfg.options)
+	defer in.Close()
+
+	sorter := sortlib.NewSorter(os.Stdout, cfg.options)
+	if _, err := io.Copy(sorter, in); err != nil {
+		sorter.Abort()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := sorter.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs разбирает флаги утилиты в параметры sortlib
+func parseArgs(args []string) (config, error) {
+	flags := flag.NewFlagSet("sort", flag.ContinueOnError)
+	column := flags.Int("k", 0, "колонка для сортировки (начиная с 1)")
+	numeric := flags.Bool("n", false, "сортировать по числовому значению")
+	reverse := flags.Bool("r", false, "сортировать в обратном порядке")
+	unique := flags.Bool("u", false, "не выводить повторяющиеся строки")
+	month := flags.Bool("M", false, "сортировать по названию месяца")
+	ignoreBlanks := flags.Bool("b", false, "игнорировать начальные пробелы")
+	check := flags.Bool("c", false, "проверять, отсортированы ли данные")
+	humanNumeric := flags.Bool("h", false, "сортировать по числовому значению с учетом суффиксов")
+
+	if err := flags.Parse(args); err != nil {
+		return config{}, err
+	}
+	if *column < 0 {
+		return config{}, fmt.Errorf("invalid column: %d", *column)
+	}
+	budget, err := sortlib.ParseSize(sortlib.DefaultBufferSize)
+	if err != nil {
+		return config{}, err
+	}
+
+	cfg := config{
+		options: sortlib.Options{
+			Order: sortlib.Order{
+				Numeric:      *numeric,
+				Month:        *month,
+				HumanNumeric: *humanNumeric,
+				IgnoreBlanks: *ignoreBlanks,
+				Reverse:      *reverse,
+			},
+			Unique:     *unique,
+			Locales:    sortlib.MonthLocales(),
+			BufferSize: budget,
+			Parallel:   sortlib.DefaultParallel(),
+		},
+		check: *check,
+		files: flags.Args(),
+	}
+	if *column > 0 {
+		cfg.options.Keys = []sortlib.Key{{Start: *column, End: *column}}
+	}
+	if len(cfg.files) == 0 {
+		cfg.files = []string{sortlib.StdinName}
+	}
+	return cfg, nil
+}
+
+// check проверяет упорядоченность первого входа и возвращает код выхода
+func check(cfg config) int {
+	in, err := sortlib.OpenInput(cfg.files[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer in.Close()
 
+	lineNum, line, err := sortlib.FindDisorder(in, cfg.options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if lineNum > 0 {
+		fmt.Fprintf(os.Stderr, "sort: %s:%d: disorder: %s\n", cfg.files[0], lineNum, line)
+		return 1
+	}
+	return 0
 }
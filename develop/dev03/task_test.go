@@ -1 +1,40 @@
 package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gosort/sortlib"
+)
+
+func TestParseArgs(t *testing.T) {
+	cfg, err := parseArgs([]string{"-k", "2", "-n", "-r", "-u", "in.txt"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []sortlib.Key{{Start: 2, End: 2}}; !reflect.DeepEqual(cfg.options.Keys, expected) {
+		t.Errorf("expected keys %v, got %v", expected, cfg.options.Keys)
+	}
+	if expected := (sortlib.Order{Numeric: true, Reverse: true}); cfg.options.Order != expected {
+		t.Errorf("expected order %+v, got %+v", expected, cfg.options.Order)
+	}
+	if !cfg.options.Unique || cfg.check {
+		t.Errorf("unexpected flags: %+v", cfg)
+	}
+	if expected := []string{"in.txt"}; !reflect.DeepEqual(cfg.files, expected) {
+		t.Errorf("expected files %v, got %v", expected, cfg.files)
+	}
+
+	cfg, err = parseArgs(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.options.Keys != nil || !reflect.DeepEqual(cfg.files, []string{sortlib.StdinName}) {
+		t.Errorf("expected whole-line sort of stdin, got %+v", cfg)
+	}
+
+	if _, err := parseArgs([]string{"-k", "-1"}); err == nil {
+		t.Errorf("expected an error for a negative column")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"gosort/sortlib"
+)
+
+// lazyOutput создает файл результата только при первой записи (или при
+// закрытии), чтобы -o мог совпадать с одним из входов: при сортировке
+// результат пишется лишь после того, как весь вход прочитан.
+type lazyOutput struct {
+	name string
+	file *os.File
+}
+
+func (o *lazyOutput) Write(p []byte) (int, error) {
+	if o.file == nil {
+		file, err := os.Create(o.name)
+		if err != nil {
+			return 0, err
+		}
+		o.file = file
+	}
+	return o.file.Write(p)
+}
+
+// Close закрывает файл, создавая его, если в него ничего не было записано
+func (o *lazyOutput) Close() error {
+	if o.file == nil {
+		file, err := os.Create(o.name)
+		if err != nil {
+			return err
+		}
+		o.file = file
+	}
+	return o.file.Close()
+}
+
+// protectInputs копирует в каталог dir входы, совпадающие с файлом output,
+// и возвращает список входов с замененными именами. Нужно для -m, где
+// вход читается одновременно с записью результата.
+func protectInputs(names []string, output, dir string) ([]string, error) {
+	outInfo, err := os.Stat(output)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, err
+	}
+
+	result := make([]string, len(names))
+	for i, name := range names {
+		result[i] = name
+		if name == sortlib.StdinName {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil || !os.SameFile(info, outInfo) {
+			continue
+		}
+		if result[i], err = copyToTemp(name, dir); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// copyToTemp копирует файл во временный файл каталога dir
+func copyToTemp(name, dir string) (string, error) {
+	in, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(dir, "input-"+filepath.Base(name)+"-")
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return "", err
+	}
+	return out.Name(), out.Close()
+}
@@ -0,0 +1,102 @@
+package sortlib
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization задает нормализацию Unicode, применяемую к ключам перед сравнением
+type Normalization int
+
+const (
+	// NoNormalization — ключи сравниваются как есть
+	NoNormalization Normalization = iota
+	// NFC — каноническая композиция: "e" + U+0301 становится "é"
+	NFC
+	// NFD — каноническая декомпозиция: "é" становится "e" + U+0301
+	NFD
+)
+
+// ParseNormalization разбирает название нормализации: "", "none", "nfc" или "nfd"
+func ParseNormalization(name string) (Normalization, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return NoNormalization, nil
+	case "nfc":
+		return NFC, nil
+	case "nfd":
+		return NFD, nil
+	}
+	return NoNormalization, fmt.Errorf("unknown normalization: %q", name)
+}
+
+// ParseLocale проверяет название локали для Options.Locale. Принимаются
+// теги BCP 47 ("ru", "en-US") и имена POSIX ("ru_RU.UTF-8"); "C" и "POSIX"
+// означают побайтовое сравнение.
+func ParseLocale(name string) error {
+	if isByteLocale(name) {
+		return nil
+	}
+	_, err := language.Parse(localeTag(name))
+	return err
+}
+
+// isByteLocale сообщает, означает ли локаль побайтовое сравнение без правил сопоставления
+func isByteLocale(name string) bool {
+	return name == "" || name == "C" || name == "POSIX"
+}
+
+// localeTag превращает имя POSIX вида "ru_RU.UTF-8@euro" в тег BCP 47 "ru-RU"
+func localeTag(name string) string {
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// newCollator создает сопоставитель по правилам Unicode Collation Algorithm
+// для локали или возвращает nil для побайтового сравнения. Сопоставитель
+// не потокобезопасен, поэтому у каждого Comparator он свой.
+func newCollator(locale string, foldCase bool) *collate.Collator {
+	if isByteLocale(locale) {
+		return nil
+	}
+	var options []collate.Option
+	if foldCase {
+		options = append(options, collate.IgnoreCase)
+	}
+	return collate.New(language.Make(localeTag(locale)), options...)
+}
+
+// normalize приводит строку к заданной нормальной форме
+func normalize(s string, form Normalization) string {
+	switch form {
+	case NFC:
+		return norm.NFC.String(s)
+	case NFD:
+		return norm.NFD.String(s)
+	}
+	return s
+}
+
+// filterKey оставляет в ключе только символы, значимые для -d и -i
+func filterKey(key string, order Order) string {
+	if !order.Dictionary && !order.IgnoreNonPrinting {
+		return key
+	}
+	return strings.Map(func(r rune) rune {
+		// Диакритические знаки в форме NFD остаются частью буквы
+		if order.Dictionary && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) && !unicode.IsMark(r) {
+			return -1
+		}
+		if order.IgnoreNonPrinting && !unicode.IsPrint(r) && r != ' ' {
+			return -1
+		}
+		return r
+	}, key)
+}
@@ -0,0 +1,525 @@
+package sortlib
+
+import (
+	"cmp"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+)
+
+// blanks — символы, которые IgnoreBlanks пропускает в начале ключа
+const blanks = " \t"
+
+// Comparator сравнивает строки согласно Options. При заданной Options.Locale
+// Comparator не потокобезопасен: каждой горутине нужен свой.
+type Comparator struct {
+	options Options
+	locales []string
+	orders  []Order // порядок каждого ключа с учетом общего
+	// collator и foldCollator сопоставляют строки по правилам локали
+	// с учетом и без учета регистра; nil при побайтовом сравнении
+	collator     *collate.Collator
+	foldCollator *collate.Collator
+}
+
+// NewComparator создает Comparator для заданных параметров
+func NewComparator(options Options) *Comparator {
+	locales := options.Locales
+	if len(locales) == 0 {
+		locales = []string{"en"}
+	}
+	orders := []Order{options.Order}
+	if len(options.Keys) > 0 {
+		orders = make([]Order, len(options.Keys))
+		for i, key := range options.Keys {
+			orders[i] = key.Order
+			if orders[i] == (Order{}) {
+				orders[i] = options.Order
+			}
+		}
+	}
+	return &Comparator{
+		options:      options,
+		locales:      locales,
+		orders:       orders,
+		collator:     newCollator(options.Locale, false),
+		foldCollator: newCollator(options.Locale, true),
+	}
+}
+
+// Compare возвращает отрицательное число, если a должна идти раньше b,
+// положительное — если позже, и 0 для равных строк. Строки с равными
+// ключами сравниваются целиком, если не задан Stable.
+func (c *Comparator) Compare(a, b string) int {
+	return c.compareRecords(a, b, c.extract(a), c.extract(b))
+}
+
+// Less сообщает, должна ли a идти раньше b
+func (c *Comparator) Less(a, b string) bool {
+	return c.Compare(a, b) < 0
+}
+
+// CompareKeys сравнивает только ключи сортировки, без сравнения строк целиком
+func (c *Comparator) CompareKeys(a, b string) int {
+	return c.compareExtracted(c.extract(a), c.extract(b))
+}
+
+// extract возвращает значения ключей записи. Разбор записи CSV или JSON
+// дорог, поэтому при сортировке значения извлекаются один раз на запись.
+func (c *Comparator) extract(record string) []keyValue {
+	keys := c.options.Keys
+	if len(keys) == 0 {
+		return []keyValue{{text: record, kind: kindText}}
+	}
+
+	switch c.options.Format {
+	case CSV, TSV:
+		return fieldValues(record, keys, c.options.Format)
+	case JSONL:
+		return jsonValues(record, keys)
+	}
+	values := make([]keyValue, len(keys))
+	for i, key := range keys {
+		values[i] = keyValue{text: extractKey(record, key), kind: kindText}
+	}
+	return values
+}
+
+// compareRecords сравнивает записи a и b по уже извлеченным ключам;
+// при равных ключах без Stable записи сравниваются целиком
+func (c *Comparator) compareRecords(a, b string, aKeys, bKeys []keyValue) int {
+	if result := c.compareExtracted(aKeys, bKeys); result != 0 || c.options.Stable {
+		return result
+	}
+	result := 0
+	if c.collator != nil {
+		result = c.collator.CompareString(a, b)
+	}
+	if result == 0 {
+		result = strings.Compare(a, b)
+	}
+	if c.options.Reverse {
+		return -result
+	}
+	return result
+}
+
+// compareExtracted сравнивает извлеченные значения ключей по порядку
+func (c *Comparator) compareExtracted(a, b []keyValue) int {
+	for i, order := range c.orders {
+		if result := c.compareOrdered(a[i], b[i], order); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// compareOrdered сравнивает значения ключа в заданном порядке с учетом Reverse.
+// Значения JSONL разных типов упорядочиваются по типу, а числа сравниваются
+// по величине, если порядок не задан явно.
+func (c *Comparator) compareOrdered(a, b keyValue, order Order) int {
+	var result int
+	switch {
+	case order.typed() || a.kind == kindText && b.kind == kindText:
+		result = c.compareValues(a.text, b.text, order)
+	case a.kind != b.kind:
+		result = cmp.Compare(a.kind, b.kind)
+	case a.kind == kindNumber:
+		result = cmp.Compare(a.number, b.number)
+	default:
+		result = c.compareValues(a.text, b.text, order)
+	}
+	if order.Reverse {
+		return -result
+	}
+	return result
+}
+
+// typed сообщает, задан ли порядок, толкующий ключ как значение определенного вида
+func (o Order) typed() bool {
+	return o.Numeric || o.Month || o.HumanNumeric || o.General || o.Version || o.Random
+}
+
+// compareValues сравнивает значения ключей
+func (c *Comparator) compareValues(a, b string, order Order) int {
+	switch {
+	case order.Month:
+		return compareMonths(a, b, c.locales)
+	case order.HumanNumeric:
+		return compareHuman(a, b)
+	case order.General:
+		return compareGeneral(a, b)
+	case order.Version:
+		return compareVersions(strings.TrimLeft(a, blanks), strings.TrimLeft(b, blanks))
+	case order.Random:
+		if order.IgnoreBlanks {
+			a = strings.TrimLeft(a, blanks)
+			b = strings.TrimLeft(b, blanks)
+		}
+		return compareRandom(a, b, c.options.Seed)
+	case order.Numeric:
+		return cmp.Compare(parseNumeric(a), parseNumeric(b))
+	}
+
+	if order.IgnoreBlanks {
+		a = strings.TrimLeft(a, blanks)
+		b = strings.TrimLeft(b, blanks)
+	}
+	a, b = c.prepareText(a, order), c.prepareText(b, order)
+
+	switch {
+	case c.collator == nil:
+		return strings.Compare(a, b)
+	case order.FoldCase:
+		return c.foldCollator.CompareString(a, b)
+	}
+	return c.collator.CompareString(a, b)
+}
+
+// prepareText нормализует и фильтрует текстовый ключ перед сравнением;
+// при побайтовом сравнении -f приводит ключ к верхнему регистру, как GNU sort
+func (c *Comparator) prepareText(key string, order Order) string {
+	key = filterKey(normalize(key, c.options.Normalization), order)
+	if order.FoldCase && c.collator == nil {
+		key = strings.ToUpper(key)
+	}
+	return key
+}
+
+// extractKey возвращает колонки key.Start..key.End строки. Как в POSIX,
+// колонка включает предшествующие ей пробельные символы, а ключ
+// заканчивается на последнем непробельном символе колонки key.End;
+// начальные пробелы отбрасывает только IgnoreBlanks.
+func extractKey(line string, key Key) string {
+	start, column := -1, 0
+	for i := 0; i < len(line); {
+		column++
+		fieldStart := i
+		for inField := false; i < len(line); {
+			r, size := utf8.DecodeRuneInString(line[i:])
+			if unicode.IsSpace(r) && inField {
+				break
+			}
+			inField = inField || !unicode.IsSpace(r)
+			i += size
+		}
+
+		if column == key.Start {
+			start = fieldStart
+		}
+		if column == key.End && start >= 0 {
+			return line[start:i]
+		}
+	}
+
+	if start < 0 {
+		return ""
+	}
+	return line[start:]
+}
+
+// monthNames содержит сокращения названий месяцев для каждой локали.
+// Сравнение идет по префиксу, поэтому "январь", "января" и "Jan" распознаются.
+var monthNames = map[string][12][]string{
+	"en": {
+		{"jan"}, {"feb"}, {"mar"}, {"apr"}, {"may"}, {"jun"},
+		{"jul"}, {"aug"}, {"sep"}, {"oct"}, {"nov"}, {"dec"},
+	},
+	"ru": {
+		{"янв"}, {"фев"}, {"мар"}, {"апр"}, {"май", "мая"}, {"июн"},
+		{"июл"}, {"авг"}, {"сен"}, {"окт"}, {"ноя"}, {"дек"},
+	},
+}
+
+// monthIndex возвращает номер месяца (1–12) в начале ключа или 0,
+// если месяц не распознан. Начальные пробелы игнорируются.
+func monthIndex(key string, locales []string) int {
+	key = strings.ToLower(strings.TrimLeft(key, blanks))
+	for _, locale := range locales {
+		for i, names := range monthNames[locale] {
+			for _, name := range names {
+				if strings.HasPrefix(key, name) {
+					return i + 1
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// compareMonths сравнивает ключи по названию месяца; нераспознанные идут первыми
+func compareMonths(left, right string, locales []string) int {
+	return cmp.Compare(monthIndex(left, locales), monthIndex(right, locales))
+}
+
+// parseNumeric разбирает число в начале ключа, как GNU sort -n: пробелы,
+// необязательный минус, цифры и дробная часть. Ключ без числа считается
+// нулем, поэтому числовые ключи никогда не сравниваются как текст.
+func parseNumeric(key string) float64 {
+	key = strings.TrimLeft(key, blanks)
+
+	end := 0
+	if strings.HasPrefix(key, "-") {
+		end++
+	}
+	for end < len(key) && isDigit(key[end]) {
+		end++
+	}
+	if end < len(key) && key[end] == '.' {
+		end++
+		for end < len(key) && isDigit(key[end]) {
+			end++
+		}
+	}
+	// Для "", "-" и "." ParseFloat возвращает 0, а для слишком длинных чисел — ±Inf
+	number, _ := strconv.ParseFloat(key[:end], 64)
+	return number
+}
+
+// humanSuffixes — суффиксы размеров в порядке возрастания
+const humanSuffixes = "KMGTPEZYRQ"
+
+// humanValue — разобранное число с суффиксом размера
+type humanValue struct {
+	sign   int     // -1, 0 или 1
+	rank   int     // 0 без суффикса, 1 для K, 2 для M и т.д.
+	number float64 // абсолютное значение без учета суффикса
+}
+
+// parseHuman разбирает число вида "1.5K" или "-2G" в начале ключа.
+// Ключ без числа считается нулем.
+func parseHuman(key string) humanValue {
+	key = strings.TrimLeft(key, blanks)
+
+	negative := strings.HasPrefix(key, "-")
+	if negative {
+		key = key[1:]
+	}
+
+	end := 0
+	for end < len(key) && (key[end] >= '0' && key[end] <= '9' || key[end] == '.') {
+		end++
+	}
+	number, err := strconv.ParseFloat(key[:end], 64)
+	if err != nil || number == 0 {
+		return humanValue{}
+	}
+
+	value := humanValue{sign: 1, number: number}
+	if negative {
+		value.sign = -1
+	}
+	if end < len(key) {
+		if i := strings.IndexByte(humanSuffixes, byte(unicode.ToUpper(rune(key[end])))); i >= 0 {
+			value.rank = i + 1
+		}
+	}
+	return value
+}
+
+// compareHuman сравнивает размеры как GNU sort -h: сначала знак,
+// затем суффикс, и только потом само число
+func compareHuman(left, right string) int {
+	l, r := parseHuman(left), parseHuman(right)
+	if c := cmp.Compare(l.sign, r.sign); c != 0 {
+		return c
+	}
+
+	c := cmp.Compare(l.rank, r.rank)
+	if c == 0 {
+		c = cmp.Compare(l.number, r.number)
+	}
+	return c * l.sign
+}
+
+// versionOrder возвращает вес символа нечисловой части версии,
+// как в Debian/GNU: '~' раньше конца строки, буквы раньше прочих символов
+func versionOrder(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return 0
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	case c == '~':
+		return -1
+	}
+	return int(c) + 256
+}
+
+// compareVersions сравнивает строки как номера версий: числовые части
+// сравниваются по значению, поэтому "v1.10" идет после "v1.9"
+func compareVersions(left, right string) int {
+	i, j := 0, 0
+	for i < len(left) || j < len(right) {
+		// Нечисловая часть
+		for i < len(left) && !isDigit(left[i]) || j < len(right) && !isDigit(right[j]) {
+			var l, r int
+			if i < len(left) {
+				l = versionOrder(left[i])
+			}
+			if j < len(right) {
+				r = versionOrder(right[j])
+			}
+			if l != r {
+				return cmp.Compare(l, r)
+			}
+			i++
+			j++
+		}
+
+		// Числовая часть: ведущие нули не учитываются
+		for i < len(left) && left[i] == '0' {
+			i++
+		}
+		for j < len(right) && right[j] == '0' {
+			j++
+		}
+		first := 0
+		for i < len(left) && isDigit(left[i]) && j < len(right) && isDigit(right[j]) {
+			if first == 0 {
+				first = cmp.Compare(int(left[i]), int(right[j]))
+			}
+			i++
+			j++
+		}
+		if i < len(left) && isDigit(left[i]) {
+			return 1
+		}
+		if j < len(right) && isDigit(right[j]) {
+			return -1
+		}
+		if first != 0 {
+			return first
+		}
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// generalValue — ключ -g: нечисла идут первыми, затем NaN, затем числа
+type generalValue struct {
+	class  int // 0 — не число, 1 — NaN, 2 — число (включая ±Inf)
+	number float64
+}
+
+// parseGeneral разбирает самый длинный префикс ключа, являющийся числом
+// с плавающей точкой (с экспонентой, inf и nan), как strtod в GNU sort -g
+func parseGeneral(key string) generalValue {
+	key = strings.TrimLeft(key, blanks)
+	end, hex := floatPrefix(key)
+	if end == 0 {
+		return generalValue{}
+	}
+
+	// strtod принимает шестнадцатеричные числа без двоичной экспоненты, а ParseFloat — нет
+	candidate := key[:end]
+	if hex && !strings.ContainsAny(candidate, "pP") {
+		candidate += "p0"
+	}
+	// Префикс уже проверен, ошибкой может быть только переполнение, и тогда number — ±Inf или 0
+	number, _ := strconv.ParseFloat(candidate, 64)
+	if math.IsNaN(number) {
+		return generalValue{class: 1}
+	}
+	return generalValue{class: 2, number: number}
+}
+
+// floatPrefix возвращает длину самого длинного префикса s, который strtod
+// разбирает как число, и сообщает, шестнадцатеричное ли оно. Префикс
+// находится за один проход, без разбора каждого из более коротких.
+func floatPrefix(s string) (int, bool) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for _, word := range []string{"infinity", "inf", "nan"} {
+		if len(s)-i >= len(word) && strings.EqualFold(s[i:i+len(word)], word) {
+			return i + len(word), false
+		}
+	}
+
+	digit, exponent := isDigit, "eE"
+	hex := len(s)-i > 2 && s[i] == '0' && (s[i+1] == 'x' || s[i+1] == 'X')
+	mantissa := i
+	if hex {
+		digit, exponent = isHexDigit, "pP"
+		mantissa += 2
+	}
+
+	end, digits := mantissa, 0
+	for end < len(s) && digit(s[end]) {
+		end++
+		digits++
+	}
+	if end < len(s) && s[end] == '.' {
+		end++
+		for end < len(s) && digit(s[end]) {
+			end++
+			digits++
+		}
+	}
+	if digits == 0 {
+		if hex {
+			// Из "0x" без цифр strtod разбирает только "0"
+			return i + 1, false
+		}
+		return 0, false
+	}
+
+	// Экспонента входит в префикс, только если за ней есть цифры
+	if end < len(s) && strings.IndexByte(exponent, s[end]) >= 0 {
+		j := end + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			end = j
+		}
+	}
+	return end, hex
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// compareGeneral сравнивает ключи как GNU sort -g
+func compareGeneral(left, right string) int {
+	l, r := parseGeneral(left), parseGeneral(right)
+	if l.class != r.class || l.class != 2 {
+		return cmp.Compare(l.class, r.class)
+	}
+	return cmp.Compare(l.number, r.number)
+}
+
+// compareRandom упорядочивает ключи по их хешу с заданным зерном.
+// Одинаковые ключи имеют одинаковый хеш и поэтому оказываются рядом,
+// а при совпадении хешей разных ключей порядок определяется самими ключами.
+func compareRandom(left, right string, seed uint64) int {
+	if c := cmp.Compare(randomHash(left, seed), randomHash(right, seed)); c != 0 {
+		return c
+	}
+	return strings.Compare(left, right)
+}
+
+// randomHash возвращает хеш FNV-1a ключа, смешанный с зерном
+func randomHash(key string, seed uint64) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], seed)
+	h.Write(buf[:])
+	h.Write([]byte(key))
+	return h.Sum64()
+}
@@ -0,0 +1,52 @@
+package sortlib
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+var (
+	// gzipMagic — начало потока gzip: сигнатура и метод deflate
+	gzipMagic = []byte{0x1f, 0x8b, 0x08}
+	// zstdMagic — начало кадра zstd
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	// bzip2Block и bzip2End — сигнатуры первого блока и конца потока bzip2,
+	// идущие после заголовка "BZh1".."BZh9"
+	bzip2Block = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2End   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// ErrZstd возвращается для входа, сжатого zstd: распаковщика zstd нет
+// в стандартной библиотеке
+var ErrZstd = errors.New("zstd-compressed input is not supported")
+
+// Decompress распознает по первым байтам вход, сжатый gzip или bzip2,
+// и возвращает распакованный поток; несжатый вход возвращается без изменений
+func Decompress(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(10)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(reader)
+	case isBzip2(magic):
+		return bzip2.NewReader(reader), nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return nil, ErrZstd
+	}
+	return reader, nil
+}
+
+// isBzip2 сообщает, начинается ли magic с заголовка потока bzip2
+func isBzip2(magic []byte) bool {
+	if len(magic) < 10 || string(magic[:3]) != "BZh" || magic[3] < '1' || magic[3] > '9' {
+		return false
+	}
+	return bytes.Equal(magic[4:], bzip2Block) || bytes.Equal(magic[4:], bzip2End)
+}
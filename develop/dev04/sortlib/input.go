@@ -0,0 +1,122 @@
+package sortlib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdinName — имя, под которым в списке входов указывается стандартный ввод
+const StdinName = "-"
+
+// OpenInput открывает вход по имени; "-" означает стандартный ввод.
+// Вход, сжатый gzip или bzip2, распаковывается (см. Decompress).
+func OpenInput(name string) (io.ReadCloser, error) {
+	var file io.ReadCloser = io.NopCloser(os.Stdin)
+	if name != StdinName {
+		var err error
+		if file, err = os.Open(name); err != nil {
+			return nil, err
+		}
+	}
+	reader, err := Decompress(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, file}, nil
+}
+
+// inputReader последовательно читает несколько входов как один поток.
+// Файлы открываются по одному, а к входу без завершающего разделителя
+// он дописывается, чтобы последняя запись не склеилась с первой записью
+// следующего входа. С Options.Header заголовки всех входов, кроме
+// первого, пропускаются.
+type inputReader struct {
+	names   []string
+	options Options
+	opened  int // число открытых входов
+	current io.ReadCloser
+	last    byte // последний прочитанный из текущего входа байт
+	empty   bool // из текущего входа еще ничего не прочитано
+}
+
+// NewInputReader создает читатель, последовательно читающий входы names
+// с разделителем и форматом записей из options; "-" означает стандартный ввод
+func NewInputReader(names []string, options Options) io.ReadCloser {
+	return &inputReader{names: names, options: options}
+}
+
+func (r *inputReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.names) == 0 {
+				return 0, io.EOF
+			}
+			current, err := OpenInput(r.names[0])
+			if err != nil {
+				return 0, err
+			}
+			r.names = r.names[1:]
+			r.opened++
+			r.current, r.empty = current, true
+			if r.options.Header && r.opened > 1 {
+				if err := r.skipHeader(); err != nil {
+					return 0, err
+				}
+			}
+		}
+
+		n, err := r.current.Read(p)
+		if n > 0 {
+			r.last, r.empty = p[n-1], false
+			return n, nil
+		}
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			delim := r.options.Terminator()
+			if !r.empty && r.last != delim && len(p) > 0 {
+				p[0] = delim
+				return 1, nil
+			}
+			continue
+		}
+		return 0, err
+	}
+}
+
+// skipHeader пропускает первую запись текущего входа
+func (r *inputReader) skipHeader() error {
+	reader := bufio.NewReader(r.current)
+	if _, err := readRecord(reader, r.options.Terminator(), r.options.Format); err != nil && err != io.EOF {
+		return err
+	}
+	r.current = struct {
+		io.Reader
+		io.Closer
+	}{reader, r.current}
+	return nil
+}
+
+// Close закрывает текущий вход, если он открыт
+func (r *inputReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// writeRecord пишет запись, завершая ее разделителем delim
+func writeRecord(w *bufio.Writer, line string, delim byte) error {
+	if _, err := w.WriteString(line); err != nil {
+		return err
+	}
+	return w.WriteByte(delim)
+}
@@ -0,0 +1,222 @@
+// Package sortlib реализует сортировку строк с семантикой утилиты sort:
+// ключи-колонки, числовой, месячный, версионный и случайный порядок,
+// устойчивую сортировку, проверку упорядоченности и внешнюю сортировку
+// с ограниченным бюджетом памяти.
+package sortlib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// DefaultBufferSize — бюджет памяти Sorter по умолчанию в формате ParseSize
+const DefaultBufferSize = "256M"
+
+// Order задает способ сравнения ключей
+type Order struct {
+	Numeric           bool // -n, по числовому значению
+	Month             bool // -M, по названию месяца
+	HumanNumeric      bool // -h, по числу с суффиксом размера
+	General           bool // -g, числа с плавающей точкой, экспонентами, inf и nan
+	Version           bool // -V, естественный порядок номеров версий
+	Random            bool // -R, случайный порядок с группировкой одинаковых ключей
+	IgnoreBlanks      bool // -b, игнорировать начальные пробелы
+	Reverse           bool // -r, обратный порядок
+	FoldCase          bool // -f, не различать регистр
+	Dictionary        bool // -d, учитывать только буквы, цифры и пробелы
+	IgnoreNonPrinting bool // -i, пропускать непечатаемые символы
+}
+
+// Key задает ключ сортировки — диапазон колонок, разделенных пробелами,
+// а в форматах CSV, TSV и JSONL — поле записи
+type Key struct {
+	Start int    // первая колонка ключа, начиная с 1
+	End   int    // последняя колонка ключа; 0 — до конца строки
+	Field string // имя колонки CSV и TSV или путь JSONL; если задано, Start и End не используются
+	Order Order  // собственный порядок ключа; если пуст, действует общий
+}
+
+// Options содержит параметры сортировки
+type Options struct {
+	Order                        // общий порядок для строк и ключей без собственного
+	Keys           []Key         // ключи сортировки; без ключей сравнивается вся строка
+	Format         Format        // формат записей
+	Header         bool          // первая запись — заголовок: выводится первым и задает имена колонок
+	Unique         bool          // -u, не выводить повторяющиеся строки
+	Stable         bool          // -s, не сравнивать строки целиком при равных ключах
+	Seed           uint64        // зерно для Random
+	Locales        []string      // локали, чьи названия месяцев распознает Month; по умолчанию "en"
+	Locale         string        // локаль сопоставления по Unicode Collation Algorithm; пусто или "C" — побайтово
+	Normalization  Normalization // нормализация Unicode ключей перед сравнением
+	ZeroTerminated bool          // -z, записи завершаются NUL, а не переводом строки
+	BufferSize     int64         // бюджет памяти Sorter в байтах; 0 — без ограничения
+	TempDir        string        // каталог для временных файлов; пусто — os.TempDir()
+	Parallel       int           // число одновременно сортируемых блоков; 0 — один
+}
+
+// Terminator возвращает разделитель записей
+func (o Options) Terminator() byte {
+	if o.ZeroTerminated {
+		return 0
+	}
+	return '\n'
+}
+
+// ParseKey разбирает описание ключа вида "2", "2,4" или "3n", "1,2rb":
+// номер первой колонки, необязательный номер последней и буквы порядка
+// (b, d, f, g, h, i, M, n, R, r, V). Как в POSIX, ключ "N" без последней
+// колонки продолжается до конца строки.
+func ParseKey(spec string) (Key, error) {
+	fields, flags := spec, ""
+	if i := strings.IndexFunc(spec, func(r rune) bool { return (r < '0' || r > '9') && r != ',' }); i >= 0 {
+		fields, flags = spec[:i], spec[i:]
+	}
+
+	var key Key
+	start, end, hasEnd := strings.Cut(fields, ",")
+	var err error
+	if key.Start, err = strconv.Atoi(start); err != nil || key.Start < 1 {
+		return Key{}, fmt.Errorf("invalid key start: %q", spec)
+	}
+	if hasEnd {
+		if key.End, err = strconv.Atoi(end); err != nil || key.End < 1 {
+			return Key{}, fmt.Errorf("invalid key end: %q", spec)
+		}
+		if key.End < key.Start {
+			return Key{}, fmt.Errorf("key end precedes start: %q", spec)
+		}
+	}
+
+	if key.Order, err = parseOrder(flags, spec); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// ParseFieldKey разбирает ключ записи CSV, TSV или JSONL вида "ПОЛЕ[:БУКВЫ]":
+// номер колонки или диапазон ("2", "2,4"), имя колонки из заголовка ("price")
+// или путь JSON ("user.age"), после двоеточия — буквы порядка, как в ParseKey.
+// Имя, содержащее двоеточие, записывается с пустым списком букв: "a:b:".
+func ParseFieldKey(spec string) (Key, error) {
+	field, flags := spec, ""
+	if i := strings.LastIndexByte(spec, ':'); i >= 0 {
+		field, flags = spec[:i], spec[i+1:]
+	}
+	if field == "" {
+		return Key{}, fmt.Errorf("empty key field: %q", spec)
+	}
+
+	if strings.Trim(field, "0123456789,") == "" {
+		key, err := ParseKey(field)
+		if err != nil {
+			return Key{}, err
+		}
+		// В записи номер без диапазона — одна колонка
+		if key.End == 0 {
+			key.End = key.Start
+		}
+		key.Order, err = parseOrder(flags, spec)
+		return key, err
+	}
+
+	order, err := parseOrder(flags, spec)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Field: field, Order: order}, nil
+}
+
+// parseOrder разбирает буквы порядка ключа spec
+func parseOrder(flags, spec string) (Order, error) {
+	var order Order
+	for _, flag := range flags {
+		switch flag {
+		case 'b':
+			order.IgnoreBlanks = true
+		case 'd':
+			order.Dictionary = true
+		case 'f':
+			order.FoldCase = true
+		case 'g':
+			order.General = true
+		case 'h':
+			order.HumanNumeric = true
+		case 'i':
+			order.IgnoreNonPrinting = true
+		case 'M':
+			order.Month = true
+		case 'n':
+			order.Numeric = true
+		case 'R':
+			order.Random = true
+		case 'r':
+			order.Reverse = true
+		case 'V':
+			order.Version = true
+		default:
+			return Order{}, fmt.Errorf("invalid key flag %q in %q", flag, spec)
+		}
+	}
+	return order, nil
+}
+
+// ParseSize разбирает размер буфера в формате GNU sort: число с суффиксом
+// b, K, M, G или T. Число без суффикса трактуется как килобайты.
+func ParseSize(value string) (int64, error) {
+	if value == "" {
+		return 0, errors.New("empty size")
+	}
+
+	multiplier := int64(1 << 10)
+	number := value[:len(value)-1]
+	switch suffix := value[len(value)-1]; suffix {
+	case 'b', 'B':
+		multiplier = 1
+	case 'k', 'K':
+		multiplier = 1 << 10
+	case 'm', 'M':
+		multiplier = 1 << 20
+	case 'g', 'G':
+		multiplier = 1 << 30
+	case 't', 'T':
+		multiplier = 1 << 40
+	default:
+		number = value
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid size: %q", value)
+	}
+	return size * multiplier, nil
+}
+
+// DefaultParallel возвращает число блоков, сортируемых одновременно по умолчанию
+func DefaultParallel() int {
+	return min(runtime.NumCPU(), 8)
+}
+
+// MonthLocales возвращает локали, чьи названия месяцев распознает Month:
+// английские распознаются всегда, к ним добавляется язык из LC_ALL, LC_TIME или LANG
+func MonthLocales() []string {
+	locales := []string{"en"}
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		lang := strings.ToLower(value)
+		if i := strings.IndexAny(lang, "_.@"); i >= 0 {
+			lang = lang[:i]
+		}
+		if _, ok := monthNames[lang]; ok && lang != "en" {
+			locales = append(locales, lang)
+		}
+		break
+	}
+	return locales
+}
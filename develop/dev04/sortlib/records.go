@@ -0,0 +1,241 @@
+package sortlib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format задает формат записей
+type Format int
+
+const (
+	// Lines — строки с колонками, разделенными пробелами, как в sort
+	Lines Format = iota
+	// CSV — записи RFC 4180: поля через запятую, кавычки, переводы строк внутри кавычек
+	CSV
+	// TSV — поля через табуляцию, без кавычек
+	TSV
+	// JSONL — по одному объекту JSON в строке, ключи задаются путями
+	JSONL
+)
+
+// ParseFormat разбирает название формата: "lines", "csv", "tsv" или "jsonl"
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "lines":
+		return Lines, nil
+	case "csv":
+		return CSV, nil
+	case "tsv":
+		return TSV, nil
+	case "jsonl", "ndjson":
+		return JSONL, nil
+	}
+	return Lines, fmt.Errorf("unknown format: %q", name)
+}
+
+// separator возвращает разделитель полей формата
+func (f Format) separator() string {
+	if f == TSV {
+		return "\t"
+	}
+	return ","
+}
+
+// recordSplitter находит концы записей в потоке байт. В CSV разделитель
+// внутри кавычек принадлежит полю; состояние кавычек сохраняется между
+// вызовами, поэтому запись может быть разбита на несколько порций.
+type recordSplitter struct {
+	format Format
+	delim  byte
+	quoted bool
+}
+
+// end возвращает индекс разделителя, завершающего запись, или -1
+func (s *recordSplitter) end(p []byte) int {
+	if s.format != CSV {
+		return bytes.IndexByte(p, s.delim)
+	}
+	for i, b := range p {
+		switch {
+		case b == '"':
+			// Экранированная кавычка "" переключает состояние дважды
+			s.quoted = !s.quoted
+		case b == s.delim && !s.quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+// readRecord читает одну запись без ограничения длины и без завершающего
+// разделителя; запись CSV продолжается, пока не закрыты кавычки
+func readRecord(reader *bufio.Reader, delim byte, format Format) (string, error) {
+	record, err := reader.ReadString(delim)
+	if format == CSV {
+		for err == nil && strings.Count(record, `"`)%2 == 1 {
+			var more string
+			more, err = reader.ReadString(delim)
+			record += more
+		}
+	}
+	return strings.TrimSuffix(record, string(delim)), err
+}
+
+// splitFields разбивает запись CSV или TSV на поля
+func splitFields(record string, format Format) []string {
+	if format == TSV {
+		return strings.Split(record, "\t")
+	}
+	reader := csv.NewReader(strings.NewReader(record))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	fields, err := reader.Read()
+	if err != nil {
+		// Запись, которую нельзя разобрать, делится по запятым как есть
+		return strings.Split(record, ",")
+	}
+	return fields
+}
+
+// resolveHeader заменяет имена колонок в ключах их номерами по строке
+// заголовка header. Для JSONL и Lines ключи не меняются.
+func (o Options) resolveHeader(header string) (Options, error) {
+	if o.Format != CSV && o.Format != TSV {
+		return o, nil
+	}
+	names := splitFields(header, o.Format)
+	keys := make([]Key, len(o.Keys))
+	for i, key := range o.Keys {
+		if key.Field != "" {
+			index := -1
+			for j, name := range names {
+				if name == key.Field {
+					index = j
+					break
+				}
+			}
+			if index < 0 {
+				return o, fmt.Errorf("unknown column: %q", key.Field)
+			}
+			key.Field, key.Start, key.End = "", index+1, index+1
+		}
+		keys[i] = key
+	}
+	o.Keys = keys
+	return o, nil
+}
+
+// valueKind — тип значения ключа. Значения разных типов упорядочиваются
+// по типу: отсутствующее поле, null, логическое, число, строка, массив, объект.
+type valueKind int
+
+const (
+	kindMissing valueKind = iota
+	kindNull
+	kindBool
+	kindNumber
+	kindText
+	kindArray
+	kindObject
+)
+
+// keyValue — извлеченное значение ключа записи. У колонок текстовых
+// форматов тип всегда kindText; типы различаются только в JSONL.
+type keyValue struct {
+	text   string
+	kind   valueKind
+	number float64
+}
+
+// fieldValues возвращает значения ключей записи CSV или TSV; ключ
+// с диапазоном колонок объединяет их через разделитель формата
+func fieldValues(record string, keys []Key, format Format) []keyValue {
+	fields := splitFields(record, format)
+	values := make([]keyValue, len(keys))
+	for i, key := range keys {
+		if key.Field != "" || key.Start > len(fields) {
+			// Имя колонки без заголовка или несуществующая колонка
+			continue
+		}
+		end := len(fields)
+		if key.End > 0 {
+			end = min(key.End, end)
+		}
+		values[i] = keyValue{text: strings.Join(fields[key.Start-1:end], format.separator()), kind: kindText}
+	}
+	return values
+}
+
+// jsonValues возвращает значения ключей записи JSONL по путям key.Field.
+// У строки, не являющейся JSON, все ключи отсутствуют.
+func jsonValues(record string, keys []Key) []keyValue {
+	values := make([]keyValue, len(keys))
+	decoder := json.NewDecoder(strings.NewReader(record))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return values
+	}
+	for i, key := range keys {
+		if value, ok := lookupPath(document, key.Field); ok {
+			values[i] = jsonValue(value)
+		}
+	}
+	return values
+}
+
+// lookupPath находит значение по пути вида "user.name" или "items.0.id";
+// числовой сегмент пути внутри массива означает индекс элемента
+func lookupPath(document any, path string) (any, bool) {
+	if path == "" {
+		return nil, false
+	}
+	value := document
+	for _, segment := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			next, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonValue превращает декодированное значение JSON в значение ключа;
+// строки сравниваются по содержимому, остальное — по записи в JSON
+func jsonValue(value any) keyValue {
+	switch v := value.(type) {
+	case nil:
+		return keyValue{text: "null", kind: kindNull}
+	case bool:
+		return keyValue{text: strconv.FormatBool(v), kind: kindBool}
+	case json.Number:
+		number, _ := v.Float64()
+		return keyValue{text: v.String(), kind: kindNumber, number: number}
+	case string:
+		return keyValue{text: v, kind: kindText}
+	}
+
+	text, _ := json.Marshal(value)
+	if _, ok := value.([]any); ok {
+		return keyValue{text: string(text), kind: kindArray}
+	}
+	return keyValue{text: string(text), kind: kindObject}
+}
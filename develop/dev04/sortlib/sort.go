@@ -0,0 +1,134 @@
+package sortlib
+
+import (
+	"bufio"
+	"io"
+	"sort"
+)
+
+// SortLines сортирует строки на месте с учетом заданных параметров и
+// возвращает результат; с Unique повторяющиеся строки удаляются.
+// С Header первая строка остается на месте, а колонки, которых нет
+// в заголовке, считаются пустыми.
+func SortLines(lines []string, options Options) []string {
+	if options.Header && len(lines) > 0 {
+		resolved, err := options.resolveHeader(lines[0])
+		if err == nil {
+			options = resolved
+		}
+		options.Header = false
+		sorted := SortLines(lines[1:], options)
+		return append(lines[:1], sorted...)
+	}
+
+	if options.Unique {
+		lines = unique(lines)
+	}
+
+	// Ключи извлекаются один раз на строку, а не при каждом сравнении
+	comparator := NewComparator(options)
+	records := make([]record, len(lines))
+	for i, line := range lines {
+		records[i] = record{line: line, keys: comparator.extract(line)}
+	}
+	less := func(i, j int) bool {
+		a, b := records[i], records[j]
+		return comparator.compareRecords(a.line, b.line, a.keys, b.keys) < 0
+	}
+	if options.Stable {
+		sort.SliceStable(records, less)
+	} else {
+		sort.Slice(records, less)
+	}
+	for i, record := range records {
+		lines[i] = record.line
+	}
+	return lines
+}
+
+// record — строка вместе с извлеченными значениями ее ключей
+type record struct {
+	line string
+	keys []keyValue
+}
+
+// unique удаляет повторяющиеся строки, сохраняя первое вхождение
+func unique(lines []string) []string {
+	seen := make(map[string]struct{})
+	var result []string
+	for _, line := range lines {
+		if _, exists := seen[line]; !exists {
+			result = append(result, line)
+			seen[line] = struct{}{}
+		}
+	}
+	return result
+}
+
+// IsSorted сообщает, упорядочены ли строки
+func IsSorted(lines []string, options Options) bool {
+	if options.Header && len(lines) > 0 {
+		if resolved, err := options.resolveHeader(lines[0]); err == nil {
+			options = resolved
+		}
+		lines = lines[1:]
+	}
+
+	comparator := NewComparator(options)
+	var prev record
+	for i, line := range lines {
+		current := record{line: line, keys: comparator.extract(line)}
+		if i > 0 && outOfOrder(prev, current, comparator, options.Unique) {
+			return false
+		}
+		prev = current
+	}
+	return true
+}
+
+// outOfOrder сообщает, нарушает ли запись current порядок после prev.
+// В строгом режиме (-u) повторяющиеся строки тоже считаются беспорядком.
+func outOfOrder(prev, current record, comparator *Comparator, strict bool) bool {
+	return comparator.compareRecords(current.line, prev.line, current.keys, prev.keys) < 0 ||
+		strict && current.line == prev.line
+}
+
+// FindDisorder читает записи из r и возвращает номер (начиная с 1) и текст
+// первой записи, нарушающей порядок. Номер 0 означает, что вход упорядочен.
+// С Header первая запись считается заголовком и не проверяется.
+func FindDisorder(r io.Reader, options Options) (int, string, error) {
+	reader := bufio.NewReader(r)
+	first := 1
+	if options.Header {
+		header, err := readRecord(reader, options.Terminator(), options.Format)
+		if err != nil && err != io.EOF {
+			return 0, "", err
+		}
+		if options, err = options.resolveHeader(header); err != nil {
+			return 0, "", err
+		}
+		first = 2
+	}
+
+	comparator := NewComparator(options)
+	var prev record
+	for lineNum := first; ; lineNum++ {
+		line, err := readRecord(reader, options.Terminator(), options.Format)
+		if err != nil && err != io.EOF {
+			return 0, "", err
+		}
+		if err == io.EOF && line == "" {
+			return 0, "", nil
+		}
+
+		current := record{line: line, keys: comparator.extract(line)}
+		if lineNum > first && outOfOrder(prev, current, comparator, options.Unique) {
+			return lineNum, line, nil
+		}
+		prev = current
+
+		if err == io.EOF {
+			return 0, "", nil
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package sortlib
+
+/*
+Внешняя сортировка: Sorter накапливает записи, пока не исчерпан бюджет памяти,
+каждый блок сортируется (до Parallel блоков одновременно) и сбрасывается
+во временный файл, после чего файлы сливаются k-путевым слиянием через кучу.
+Если весь вход уместился в один блок, временные файлы не создаются.
+*/
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// maxMergeFiles ограничивает число одновременно открытых файлов при слиянии
+	maxMergeFiles = 16
+	// lineOverhead — примерные накладные расходы на хранение одной строки в срезе
+	lineOverhead = 16
+)
+
+// Sorter принимает записи через Write или WriteLine и при Close пишет их
+// в отсортированном виде в исходный io.Writer, удерживая в памяти не больше
+// Options.BufferSize байт входных данных
+type Sorter struct {
+	w        io.Writer
+	options  Options
+	budget   int64 // бюджет одного блока
+	splitter recordSplitter
+	pending  []byte
+	lines    []string
+	size     int64
+	header   *string // заголовок при Options.Header, выводится первым
+
+	dir    string   // каталог временных файлов, создается при первом сбросе
+	chunks []string // файлы блоков в порядке входа
+	wg     sync.WaitGroup
+	sem    chan struct{}
+	mu     sync.Mutex
+	err    error
+}
+
+// NewSorter создает Sorter, пишущий результат в w
+func NewSorter(w io.Writer, options Options) *Sorter {
+	parallel := max(options.Parallel, 1)
+	return &Sorter{
+		w:        w,
+		options:  options,
+		splitter: recordSplitter{format: options.Format, delim: options.Terminator()},
+		// Бюджет делится между одновременно сортируемыми блоками
+		budget: options.BufferSize / int64(parallel),
+		sem:    make(chan struct{}, parallel),
+	}
+}
+
+// Write принимает поток записей, разделенных Options.Terminator().
+// Неполная последняя запись дописывается при следующем Write или при Close.
+func (s *Sorter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		i := s.splitter.end(p)
+		if i < 0 {
+			s.pending = append(s.pending, p...)
+			break
+		}
+
+		line := string(p[:i])
+		if len(s.pending) > 0 {
+			line = string(s.pending) + line
+			s.pending = s.pending[:0]
+		}
+		if err := s.WriteLine(line); err != nil {
+			return 0, err
+		}
+		p = p[i+1:]
+	}
+	return n, s.failed()
+}
+
+// WriteLine добавляет одну запись. С Options.Header первая запись
+// становится заголовком: по ней определяются номера именованных колонок.
+func (s *Sorter) WriteLine(line string) error {
+	if s.options.Header && s.header == nil {
+		options, err := s.options.resolveHeader(line)
+		if err != nil {
+			return err
+		}
+		// Блоки и их слияние уже не содержат заголовка
+		s.options, s.header = options, &line
+		s.options.Header = false
+		return nil
+	}
+
+	s.lines = append(s.lines, line)
+	s.size += int64(len(line)) + lineOverhead
+	if s.options.BufferSize > 0 && s.size >= s.budget {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	return s.failed()
+}
+
+// Close сортирует накопленные записи, пишет их в w и удаляет временные файлы
+func (s *Sorter) Close() error {
+	defer func() {
+		if s.dir != "" {
+			os.RemoveAll(s.dir)
+		}
+	}()
+
+	if len(s.pending) > 0 {
+		if err := s.WriteLine(string(s.pending)); err != nil {
+			return err
+		}
+		s.pending = nil
+	}
+
+	writer := bufio.NewWriter(s.w)
+	if s.header != nil {
+		if err := writeRecord(writer, *s.header, s.options.Terminator()); err != nil {
+			return err
+		}
+	}
+	if len(s.chunks) == 0 {
+		for _, line := range SortLines(s.lines, s.options) {
+			if err := writeRecord(writer, line, s.options.Terminator()); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+
+	if len(s.lines) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	s.wg.Wait()
+	if err := s.failed(); err != nil {
+		return err
+	}
+
+	if err := mergeFiles(s.chunks, writer, s.dir, s.options); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// Abort прекращает сортировку и удаляет временные файлы, ничего не записывая в w
+func (s *Sorter) Abort() {
+	s.wg.Wait()
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+	s.lines, s.pending = nil, nil
+}
+
+// failed возвращает первую ошибку фоновой сортировки блоков
+func (s *Sorter) failed() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// spill отдает накопленный блок на сортировку и запись во временный файл
+func (s *Sorter) spill() error {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp(s.options.TempDir, "sort")
+		if err != nil {
+			return err
+		}
+		s.dir = dir
+	}
+
+	s.mu.Lock()
+	s.chunks = append(s.chunks, "")
+	index := len(s.chunks) - 1
+	s.mu.Unlock()
+
+	lines := s.lines
+	s.lines, s.size = nil, 0
+
+	s.sem <- struct{}{}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.sem }()
+
+		name, err := writeChunk(s.dir, index, lines, s.options)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err != nil {
+			if s.err == nil {
+				s.err = err
+			}
+			return
+		}
+		s.chunks[index] = name
+	}()
+	return nil
+}
+
+// writeChunk сортирует блок строк и записывает его во временный файл
+func writeChunk(dir string, index int, lines []string, options Options) (string, error) {
+	sorted := SortLines(lines, options)
+
+	file, err := os.CreateTemp(dir, fmt.Sprintf("chunk%06d-", index))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, line := range sorted {
+		if err := writeRecord(writer, line, options.Terminator()); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
+
+// MergeFiles сливает уже отсортированные файлы в w без пересортировки;
+// "-" означает стандартный ввод
+func MergeFiles(w io.Writer, options Options, names ...string) error {
+	dir, err := os.MkdirTemp(options.TempDir, "sort")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	writer := bufio.NewWriter(w)
+	if err := mergeFiles(names, writer, dir, options); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// mergeFiles сливает отсортированные файлы в w. Если файлов больше
+// maxMergeFiles, они предварительно сливаются группами в промежуточные файлы
+// каталога dir; удаляются только файлы из этого каталога.
+func mergeFiles(files []string, w *bufio.Writer, dir string, options Options) error {
+	for len(files) > maxMergeFiles {
+		var next []string
+		for start := 0; start < len(files); start += maxMergeFiles {
+			group := files[start:min(start+maxMergeFiles, len(files))]
+			if len(group) == 1 {
+				next = append(next, group[0])
+				continue
+			}
+
+			file, err := os.CreateTemp(dir, "merge-")
+			if err != nil {
+				return err
+			}
+			writer := bufio.NewWriter(file)
+			err = mergeGroup(group, writer, dir, options)
+			if err == nil {
+				err = writer.Flush()
+			}
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+			for _, name := range group {
+				if filepath.Dir(name) == dir {
+					os.Remove(name)
+				}
+			}
+			next = append(next, file.Name())
+		}
+		files = next
+	}
+	return mergeGroup(files, w, dir, options)
+}
+
+// mergeGroup выполняет k-путевое слияние файлов через кучу. Файлы должны
+// идти в порядке входа: со Stable при равных ключах побеждает более ранний файл.
+// С Header у каждого файла есть заголовок; выводится первый непустой из них.
+// Входы пользователя открываются через OpenInput, промежуточные файлы
+// каталога dir — как есть.
+func mergeGroup(files []string, w *bufio.Writer, dir string, options Options) error {
+	var (
+		sources       []*mergeSource
+		headerWritten bool
+	)
+	for index, name := range files {
+		var file io.ReadCloser
+		var err error
+		if filepath.Dir(name) == dir {
+			file, err = os.Open(name)
+		} else {
+			file, err = OpenInput(name)
+		}
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		source := &mergeSource{reader: bufio.NewReader(file), delim: options.Terminator(), format: options.Format, index: index}
+		if options.Header {
+			header, err := readRecord(source.reader, source.delim, source.format)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if header != "" && !headerWritten {
+				if options, err = options.resolveHeader(header); err != nil {
+					return err
+				}
+				if err := writeRecord(w, header, options.Terminator()); err != nil {
+					return err
+				}
+				headerWritten = true
+			}
+		}
+		sources = append(sources, source)
+	}
+
+	comparator := NewComparator(options)
+	h := &mergeHeap{comparator: comparator, stable: options.Stable}
+	for _, source := range sources {
+		source.comparator = comparator
+		ok, err := source.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.items = append(h.items, source)
+		}
+	}
+	heap.Init(h)
+
+	var (
+		prev     string
+		prevKeys []keyValue
+		hasPrev  bool
+		// run — строки текущей серии равных ключей; нужна для Unique вместе
+		// со Stable, когда одинаковые строки могут быть разделены другими
+		// с тем же ключом
+		run map[string]struct{}
+	)
+	for h.Len() > 0 {
+		source := h.items[0]
+		line := source.line
+
+		duplicate := false
+		if options.Unique && hasPrev {
+			if options.Stable {
+				if comparator.compareExtracted(source.keys, prevKeys) != 0 {
+					run = nil
+				}
+				_, duplicate = run[line]
+			} else {
+				duplicate = line == prev
+			}
+		}
+		if options.Unique && options.Stable {
+			if run == nil {
+				run = make(map[string]struct{})
+			}
+			run[line] = struct{}{}
+		}
+		if !duplicate {
+			if err := writeRecord(w, line, options.Terminator()); err != nil {
+				return err
+			}
+		}
+		prev, prevKeys, hasPrev = line, source.keys, true
+
+		ok, err := source.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+// mergeSource — текущая запись одного из сливаемых файлов
+type mergeSource struct {
+	reader     *bufio.Reader
+	comparator *Comparator
+	line       string
+	keys       []keyValue // значения ключей line
+	delim      byte
+	format     Format
+	index      int // порядковый номер файла
+}
+
+// next переходит к следующей записи; false означает конец файла
+func (s *mergeSource) next() (bool, error) {
+	line, err := readRecord(s.reader, s.delim, s.format)
+	if err == io.EOF {
+		if line == "" {
+			return false, nil
+		}
+		err = nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.line, s.keys = line, s.comparator.extract(line)
+	return true, nil
+}
+
+// mergeHeap упорядочивает источники по их текущей записи
+type mergeHeap struct {
+	items      []*mergeSource
+	comparator *Comparator
+	stable     bool
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	c := h.comparator.compareRecords(a.line, b.line, a.keys, b.keys)
+	if c == 0 && h.stable {
+		return a.index < b.index
+	}
+	return c < 0
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x any) { h.items = append(h.items, x.(*mergeSource)) }
+
+func (h *mergeHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
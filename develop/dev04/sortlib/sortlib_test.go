@@ -0,0 +1,830 @@
+package sortlib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// column возвращает ключ из одной колонки (начиная с 1)
+func column(n int) []Key {
+	return []Key{{Start: n, End: n}}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		err      bool
+	}{
+		{"100b", 100, false},
+		{"2", 2 << 10, false},
+		{"4K", 4 << 10, false},
+		{"3M", 3 << 20, false},
+		{"1G", 1 << 30, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"-5K", 0, true},
+		{"10X", 0, true},
+	}
+
+	for _, test := range tests {
+		result, err := ParseSize(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("ParseSize(%q): unexpected error status: got %v, want error: %v", test.input, err, test.err)
+		}
+		if result != test.expected {
+			t.Errorf("ParseSize(%q) = %d; want %d", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Key
+		err      bool
+	}{
+		{"2", Key{Start: 2}, false},
+		{"2,4", Key{Start: 2, End: 4}, false},
+		{"3n", Key{Start: 3, Order: Order{Numeric: true}}, false},
+		{"1,2rb", Key{Start: 1, End: 2, Order: Order{Reverse: true, IgnoreBlanks: true}}, false},
+		{"0", Key{}, true},
+		{"3,2", Key{}, true},
+		{"2x", Key{}, true},
+		{"n", Key{}, true},
+	}
+
+	for _, test := range tests {
+		result, err := ParseKey(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("ParseKey(%q): unexpected error status: got %v, want error: %v", test.input, err, test.err)
+		}
+		if result != test.expected {
+			t.Errorf("ParseKey(%q) = %+v; want %+v", test.input, result, test.expected)
+		}
+	}
+}
+
+// randomLines генерирует воспроизводимый набор строк с повторами
+func randomLines(n int) []string {
+	rng := rand.New(rand.NewSource(1))
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d word%d %d", rng.Intn(50), rng.Intn(20), rng.Intn(1000))
+	}
+	return lines
+}
+
+// sortWithSorter пропускает input через Sorter и возвращает результат
+// и число блоков, сброшенных во временные файлы
+func sortWithSorter(t *testing.T, input string, options Options) (string, int) {
+	t.Helper()
+	var output bytes.Buffer
+	sorter := NewSorter(&output, options)
+	if _, err := io.Copy(sorter, strings.NewReader(input)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chunks := len(sorter.chunks)
+	if err := sorter.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return output.String(), chunks
+}
+
+func TestSorterSpills(t *testing.T) {
+	lines := randomLines(2000)
+	input := strings.Join(lines, "\n") + "\n"
+
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{"plain", Options{}},
+		{"numeric column", Options{Order: Order{Numeric: true}, Keys: column(1)}},
+		{"reverse unique", Options{Order: Order{Reverse: true}, Keys: column(2), Unique: true}},
+		{"two keys", Options{Keys: []Key{{Start: 2, End: 2}, {Start: 1, End: 1, Order: Order{Numeric: true, Reverse: true}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			options := tt.options
+			options.BufferSize = 512
+			options.TempDir = dir
+			options.Parallel = 3
+
+			output, chunks := sortWithSorter(t, input, options)
+			if chunks <= maxMergeFiles {
+				t.Fatalf("expected more than %d chunks to force multi-pass merge, got %d", maxMergeFiles, chunks)
+			}
+
+			expected := SortLines(append([]string(nil), lines...), tt.options)
+			result := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("external sort differs from in-memory sort")
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("expected temp dir to be cleaned up, found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestSorterInMemory(t *testing.T) {
+	options := Options{BufferSize: 1 << 20, TempDir: t.TempDir(), Parallel: 1}
+
+	output, chunks := sortWithSorter(t, "b\na\nc", options)
+	if chunks != 0 {
+		t.Errorf("expected no spilled chunks, got %d", chunks)
+	}
+	if expected := "a\nb\nc\n"; output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+// Ожидаемые результаты получены GNU sort 9.1 при LC_ALL=C; случай
+// с русскими месяцами построен по тем же правилам -M.
+func TestSortLinesGNUCorpus(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		options  Options
+		expected []string
+	}{
+		{
+			name:     "month names",
+			lines:    []string{"Feb x", "  jan", "DEC", "foo", "Mar", " aug"},
+			options:  Options{Order: Order{Month: true}},
+			expected: []string{"foo", "  jan", "Feb x", "Mar", " aug", "DEC"},
+		},
+		{
+			name:     "month column",
+			lines:    []string{"x Feb", "y jan", "z 3", "w Dec"},
+			options:  Options{Order: Order{Month: true}, Keys: column(2)},
+			expected: []string{"z 3", "y jan", "x Feb", "w Dec"},
+		},
+		{
+			name:     "russian month names",
+			lines:    []string{"март", "Января", "декабрь", "15 мая", "мая", "foo", "февраль", "Jun"},
+			options:  Options{Order: Order{Month: true}, Locales: []string{"en", "ru"}},
+			expected: []string{"15 мая", "foo", "Января", "февраль", "март", "мая", "Jun", "декабрь"},
+		},
+		{
+			name:     "human numeric",
+			lines:    []string{"1G", "2M", "1K", "1500K", "10", "-1M", "0.5G"},
+			options:  Options{Order: Order{HumanNumeric: true}},
+			expected: []string{"-1M", "10", "1K", "1500K", "2M", "0.5G", "1G"},
+		},
+		{
+			name:     "human numeric reverse",
+			lines:    []string{"5K", "3", "1M", "-2K"},
+			options:  Options{Order: Order{HumanNumeric: true, Reverse: true}},
+			expected: []string{"1M", "5K", "3", "-2K"},
+		},
+		{
+			name:     "ignore leading blanks",
+			lines:    []string{"  b", "a", " c"},
+			options:  Options{Order: Order{IgnoreBlanks: true}},
+			expected: []string{"a", "  b", " c"},
+		},
+		{
+			name:     "leading blanks count without -b",
+			lines:    []string{"  b", "a", " c"},
+			options:  Options{},
+			expected: []string{"  b", " c", "a"},
+		},
+		{
+			name:     "numeric column",
+			lines:    []string{"b  2", "a 10", "c 1"},
+			options:  Options{Order: Order{Numeric: true}, Keys: column(2)},
+			expected: []string{"c 1", "b  2", "a 10"},
+		},
+		{
+			name:     "numeric without numbers",
+			lines:    []string{"3", "abc", "10", "", "2", "-1", "-"},
+			options:  Options{Order: Order{Numeric: true}},
+			expected: []string{"-1", "", "-", "abc", "2", "3", "10"},
+		},
+		{
+			name:     "numeric prefix",
+			lines:    []string{"2x", "10.5.1", " 1.5kg", "-.5", "10"},
+			options:  Options{Order: Order{Numeric: true}},
+			expected: []string{"-.5", " 1.5kg", "2x", "10", "10.5.1"},
+		},
+		{
+			name:     "column includes leading blanks",
+			lines:    []string{"b 10", "a  2"},
+			options:  Options{Keys: []Key{{Start: 2, End: 2}}},
+			expected: []string{"a  2", "b 10"},
+		},
+		{
+			name:     "key to end of line",
+			lines:    []string{"b x 1", "a x 2"},
+			options:  Options{Keys: []Key{{Start: 2}}},
+			expected: []string{"b x 1", "a x 2"},
+		},
+		{
+			name:     "column with -b",
+			lines:    []string{"a  2", "b 10"},
+			options:  Options{Keys: []Key{{Start: 2, End: 2, Order: Order{IgnoreBlanks: true}}}},
+			expected: []string{"b 10", "a  2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SortLines(append([]string(nil), tt.lines...), tt.options)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFindDisorder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  Options
+		lineNum  int
+		expected string
+	}{
+		{"sorted", "a\nb\nc\n", Options{}, 0, ""},
+		{"first disorder", "a\nc\nb\nd\n", Options{}, 3, "b"},
+		{"numeric", "9\n10\n", Options{Order: Order{Numeric: true}}, 0, ""},
+		{"numeric ignored", "9\n10\n", Options{}, 2, "10"},
+		{"numeric text is zero", "abc\n2\n", Options{Order: Order{Numeric: true}}, 0, ""},
+		{"numeric empty line", "-1\n\n0\nx\n", Options{Order: Order{Numeric: true}}, 0, ""},
+		{"numeric after text", "3\nabc\n", Options{Order: Order{Numeric: true}}, 2, "abc"},
+		{"duplicates allowed", "a\na\n", Options{}, 0, ""},
+		{"duplicates with -u", "a\na\n", Options{Unique: true}, 2, "a"},
+		{"column key", "b 1\na 2\n", Options{Keys: column(2)}, 0, ""},
+		{"reverse", "c\nb\nd", Options{Order: Order{Reverse: true}}, 3, "d"},
+		{"months", "jan\nFeb\nmar\n", Options{Order: Order{Month: true}}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lineNum, line, err := FindDisorder(strings.NewReader(tt.input), tt.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lineNum != tt.lineNum || line != tt.expected {
+				t.Errorf("expected disorder at %d (%q), got %d (%q)", tt.lineNum, tt.expected, lineNum, line)
+			}
+		})
+	}
+}
+
+func TestSortLinesOrderingModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		options  Options
+		expected []string
+	}{
+		{
+			name:     "version",
+			lines:    []string{"v1.10", "v1.9", "v1.9a", "v1.9~rc1", "file-2.tar.gz", "file-10.tar.gz", "v1.010", "1.2"},
+			options:  Options{Order: Order{Version: true}},
+			expected: []string{"1.2", "file-2.tar.gz", "file-10.tar.gz", "v1.9~rc1", "v1.9", "v1.9a", "v1.010", "v1.10"},
+		},
+		{
+			name:     "version column",
+			lines:    []string{"b v2.0", "a v10.1", "c v2.0-rc1"},
+			options:  Options{Order: Order{Version: true}, Keys: column(2)},
+			expected: []string{"b v2.0", "c v2.0-rc1", "a v10.1"},
+		},
+		{
+			name:     "general numeric",
+			lines:    []string{"1e3", "abc", "nan", "-inf", "2.5", "inf", "-1e-2", "0x10"},
+			options:  Options{Order: Order{General: true}},
+			expected: []string{"abc", "nan", "-inf", "-1e-2", "2.5", "0x10", "1e3", "inf"},
+		},
+		{
+			name:     "general numeric prefixes",
+			lines:    []string{"2e1x", "1e", "-", "1_000", "0x", "0x1.8p1 9"},
+			options:  Options{Order: Order{General: true}},
+			expected: []string{"-", "0x", "1_000", "1e", "0x1.8p1 9", "2e1x"},
+		},
+		{
+			name:     "stable keeps input order",
+			lines:    []string{"c 1", "b 2", "a 1", "d 2"},
+			options:  Options{Order: Order{Numeric: true}, Keys: column(2), Stable: true},
+			expected: []string{"c 1", "a 1", "b 2", "d 2"},
+		},
+		{
+			name:     "stable reverse",
+			lines:    []string{"c 1", "b 2", "a 1", "d 2"},
+			options:  Options{Order: Order{Numeric: true, Reverse: true}, Keys: column(2), Stable: true},
+			expected: []string{"b 2", "d 2", "c 1", "a 1"},
+		},
+		{
+			name:     "last resort without stable",
+			lines:    []string{"c 1", "b 2", "a 1", "d 2"},
+			options:  Options{Order: Order{Numeric: true}, Keys: column(2)},
+			expected: []string{"a 1", "c 1", "b 2", "d 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SortLines(append([]string(nil), tt.lines...), tt.options)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSortLinesRandom(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("row%d key%d", i, i%7))
+	}
+	options := Options{Order: Order{Random: true}, Keys: column(2), Seed: 42}
+
+	first := SortLines(append([]string(nil), lines...), options)
+	second := SortLines(append([]string(nil), lines...), options)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same order for the same seed")
+	}
+
+	// Строки с одинаковым ключом должны идти одной группой
+	seen := make(map[string]bool)
+	prev := ""
+	for _, line := range first {
+		key := extractKey(line, column(2)[0])
+		if key != prev && seen[key] {
+			t.Fatalf("key %q is split into several groups: %q", key, first)
+		}
+		seen[key] = true
+		prev = key
+	}
+
+	options.Seed = 7
+	other := SortLines(append([]string(nil), lines...), options)
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("expected different seeds to give different orders")
+	}
+}
+
+func TestSorterStable(t *testing.T) {
+	var lines []string
+	for i := 0; i < 500; i++ {
+		lines = append(lines, fmt.Sprintf("%d %d", i%5, i))
+	}
+	input := strings.Join(lines, "\n") + "\n"
+
+	options := Options{Order: Order{Numeric: true}, Keys: column(1), Stable: true, BufferSize: 256, TempDir: t.TempDir(), Parallel: 2}
+	output, _ := sortWithSorter(t, input, options)
+
+	expected := SortLines(append([]string(nil), lines...), options)
+	result := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("external stable sort differs from in-memory stable sort")
+	}
+}
+
+func TestSorterStableUnique(t *testing.T) {
+	input := "k a\nk b\nk a\nj c\nk b\n"
+	options := Options{Keys: column(1), Stable: true, Unique: true, BufferSize: 16, TempDir: t.TempDir(), Parallel: 1}
+
+	output, _ := sortWithSorter(t, input, options)
+	if expected := "j c\nk a\nk b\n"; output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestIsSorted(t *testing.T) {
+	options := Options{Order: Order{Numeric: true}, Keys: column(2)}
+	if !IsSorted([]string{"b 1", "a 2", "c 10"}, options) {
+		t.Errorf("expected lines to be sorted")
+	}
+	if IsSorted([]string{"b 1", "c 10", "a 2"}, options) {
+		t.Errorf("expected lines not to be sorted")
+	}
+}
+
+func TestExtractKey(t *testing.T) {
+	tests := []struct {
+		line     string
+		key      Key
+		expected string
+	}{
+		{"  a  b\tc ", Key{Start: 1, End: 1}, "  a"},
+		{"  a  b\tc ", Key{Start: 2, End: 3}, "  b\tc"},
+		{"  a  b\tc ", Key{Start: 2}, "  b\tc "},
+		{"  a  b\tc ", Key{Start: 4}, " "},
+		{"a b", Key{Start: 3, End: 3}, ""},
+		{"я ёж", Key{Start: 2, End: 2}, " ёж"},
+	}
+
+	for _, test := range tests {
+		if result := extractKey(test.line, test.key); result != test.expected {
+			t.Errorf("extractKey(%q, %+v) = %q; want %q", test.line, test.key, result, test.expected)
+		}
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i, content := range []string{"a\nc\ne\n", "b\nd", "a\nf\n"} {
+		name := filepath.Join(dir, fmt.Sprintf("input%d.txt", i))
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, name)
+	}
+
+	var output bytes.Buffer
+	if err := MergeFiles(&output, Options{Unique: true, TempDir: t.TempDir()}, names...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "a\nb\nc\nd\ne\nf\n"; output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+}
+
+func TestSortLinesCollation(t *testing.T) {
+	russian := []string{"ёлка", "елка", "Яблоко", "арбуз", "Ёж", "ель", "жук"}
+	english := []string{"b", "A", "a", "B", "résumé", "resume", "Resume"}
+
+	tests := []struct {
+		name     string
+		lines    []string
+		options  Options
+		expected []string
+	}{
+		{
+			name:     "russian bytewise",
+			lines:    russian,
+			options:  Options{},
+			expected: []string{"Ёж", "Яблоко", "арбуз", "елка", "ель", "жук", "ёлка"},
+		},
+		{
+			name:     "russian collation",
+			lines:    russian,
+			options:  Options{Locale: "ru_RU.UTF-8"},
+			expected: []string{"арбуз", "Ёж", "елка", "ёлка", "ель", "жук", "Яблоко"},
+		},
+		{
+			name:     "russian collation reverse",
+			lines:    russian,
+			options:  Options{Order: Order{Reverse: true}, Locale: "ru"},
+			expected: []string{"Яблоко", "жук", "ель", "ёлка", "елка", "Ёж", "арбуз"},
+		},
+		{
+			name:     "russian fold case bytewise",
+			lines:    []string{"яма", "Ель", "ёж", "Арка"},
+			options:  Options{Order: Order{FoldCase: true}},
+			expected: []string{"ёж", "Арка", "Ель", "яма"},
+		},
+		{
+			name:     "english collation",
+			lines:    english,
+			options:  Options{Locale: "en"},
+			expected: []string{"a", "A", "b", "B", "resume", "Resume", "résumé"},
+		},
+		{
+			name:     "english fold case bytewise",
+			lines:    english,
+			options:  Options{Order: Order{FoldCase: true}},
+			expected: []string{"A", "a", "B", "b", "Resume", "resume", "résumé"},
+		},
+		{
+			name:     "dictionary order",
+			lines:    []string{"b-c", "ba", "b c"},
+			options:  Options{Order: Order{Dictionary: true}},
+			expected: []string{"b c", "ba", "b-c"},
+		},
+		{
+			name:     "ignore non-printing",
+			lines:    []string{"b", "\x01\x02a", "c"},
+			options:  Options{Order: Order{IgnoreNonPrinting: true}},
+			expected: []string{"\x01\x02a", "b", "c"},
+		},
+		{
+			name:     "key with fold case flag",
+			lines:    []string{"1 b", "2 A", "3 a"},
+			options:  Options{Keys: []Key{{Start: 2, End: 2, Order: Order{FoldCase: true}}}},
+			expected: []string{"2 A", "3 a", "1 b"},
+		},
+		{
+			name:     "decomposed without normalization",
+			lines:    []string{"\u00e9a", "e\u0301b", "f"},
+			options:  Options{},
+			expected: []string{"e\u0301b", "f", "\u00e9a"},
+		},
+		{
+			name:     "decomposed with NFD",
+			lines:    []string{"\u00e9a", "e\u0301b", "f"},
+			options:  Options{Normalization: NFD},
+			expected: []string{"\u00e9a", "e\u0301b", "f"},
+		},
+		{
+			name:     "decomposed with NFC",
+			lines:    []string{"e\u0301b", "\u00e9a", "f"},
+			options:  Options{Normalization: NFC},
+			expected: []string{"f", "\u00e9a", "e\u0301b"},
+		},
+		{
+			name:     "decomposed with collation",
+			lines:    []string{"e\u0301b", "\u00e9a", "f"},
+			options:  Options{Locale: "en"},
+			expected: []string{"\u00e9a", "e\u0301b", "f"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SortLines(append([]string(nil), tt.lines...), tt.options)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseLocaleAndNormalization(t *testing.T) {
+	for _, name := range []string{"", "C", "POSIX", "ru", "ru_RU.UTF-8", "en-US"} {
+		if err := ParseLocale(name); err != nil {
+			t.Errorf("ParseLocale(%q): unexpected error: %v", name, err)
+		}
+	}
+	if err := ParseLocale("not a locale!"); err == nil {
+		t.Errorf("ParseLocale: expected an error for an invalid locale")
+	}
+
+	if form, err := ParseNormalization("NFD"); err != nil || form != NFD {
+		t.Errorf("ParseNormalization(\"NFD\") = %v, %v; want NFD", form, err)
+	}
+	if _, err := ParseNormalization("nfkc"); err == nil {
+		t.Errorf("ParseNormalization: expected an error for an unsupported form")
+	}
+}
+
+func TestParseFieldKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Key
+		err      bool
+	}{
+		{"price", Key{Field: "price"}, false},
+		{"price:nr", Key{Field: "price", Order: Order{Numeric: true, Reverse: true}}, false},
+		{"user.age:n", Key{Field: "user.age", Order: Order{Numeric: true}}, false},
+		{"2", Key{Start: 2, End: 2}, false},
+		{"2,3:f", Key{Start: 2, End: 3, Order: Order{FoldCase: true}}, false},
+		{"a:b:", Key{Field: "a:b"}, false},
+		{":n", Key{}, true},
+		{"price:x", Key{}, true},
+		{"0:n", Key{}, true},
+	}
+
+	for _, test := range tests {
+		result, err := ParseFieldKey(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("ParseFieldKey(%q): unexpected error status: got %v, want error: %v", test.input, err, test.err)
+		}
+		if result != test.expected {
+			t.Errorf("ParseFieldKey(%q) = %+v; want %+v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestSortRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  Options
+		expected string
+	}{
+		{
+			name:     "csv by column name with quoted fields",
+			input:    "name,price\n\"Smith, J\",10\n\"multi\nline\",2\nplain,\"15\"\n",
+			options:  Options{Format: CSV, Header: true, Keys: []Key{{Field: "price", Order: Order{Numeric: true}}}},
+			expected: "name,price\n\"multi\nline\",2\n\"Smith, J\",10\nplain,\"15\"\n",
+		},
+		{
+			name:     "csv by index ignores header",
+			input:    "b,1\na,2\nc,0\n",
+			options:  Options{Format: CSV, Keys: []Key{{Start: 1, End: 1}}},
+			expected: "a,2\nb,1\nc,0\n",
+		},
+		{
+			name:     "csv crlf and missing column",
+			input:    "id,tag\r\n2,x\r\n1\r\n3,a\r\n",
+			options:  Options{Format: CSV, Header: true, Keys: []Key{{Field: "tag"}}},
+			expected: "id,tag\r\n1\r\n3,a\r\n2,x\r\n",
+		},
+		{
+			name:     "tsv keeps commas and quotes",
+			input:    "city\tpop\nb,\"x\"\t5\na\t10\n",
+			options:  Options{Format: TSV, Header: true, Keys: []Key{{Field: "pop", Order: Order{Numeric: true, Reverse: true}}}},
+			expected: "city\tpop\na\t10\nb,\"x\"\t5\n",
+		},
+		{
+			name:    "jsonl typed comparison",
+			input:   `{"v":10}` + "\n" + `{"v":"9"}` + "\n" + `{"v":9.5}` + "\n" + `{"v":null}` + "\n" + `{}` + "\n" + `{"v":true}` + "\n" + `not json` + "\n",
+			options: Options{Format: JSONL, Keys: []Key{{Field: "v"}}},
+			expected: `not json` + "\n" + `{}` + "\n" + `{"v":null}` + "\n" + `{"v":true}` + "\n" + `{"v":9.5}` + "\n" +
+				`{"v":10}` + "\n" + `{"v":"9"}` + "\n",
+		},
+		{
+			name:     "jsonl nested path and array index",
+			input:    `{"u":{"tags":["b"]},"id":1}` + "\n" + `{"u":{"tags":["a","z"]},"id":2}` + "\n",
+			options:  Options{Format: JSONL, Keys: []Key{{Field: "u.tags.0"}}},
+			expected: `{"u":{"tags":["a","z"]},"id":2}` + "\n" + `{"u":{"tags":["b"]},"id":1}` + "\n",
+		},
+		{
+			name:     "jsonl explicit numeric order on strings",
+			input:    `{"v":"10"}` + "\n" + `{"v":"9"}` + "\n",
+			options:  Options{Format: JSONL, Keys: []Key{{Field: "v", Order: Order{Numeric: true}}}},
+			expected: `{"v":"9"}` + "\n" + `{"v":"10"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, budget := range []int64{0, 32} {
+				options := tt.options
+				options.BufferSize = budget
+				options.TempDir = t.TempDir()
+				if output, _ := sortWithSorter(t, tt.input, options); output != tt.expected {
+					t.Errorf("budget %d: expected %q, got %q", budget, tt.expected, output)
+				}
+			}
+		})
+	}
+}
+
+func TestSortLinesHeaderUnique(t *testing.T) {
+	lines := []string{"name,age", "bob,30", "alice,25", "bob,30", "carol,20"}
+	options := Options{Format: CSV, Header: true, Unique: true, Keys: []Key{{Field: "age", Order: Order{Numeric: true}}}}
+
+	result := SortLines(lines, options)
+	if expected := []string{"name,age", "carol,20", "alice,25", "bob,30"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestSortRecordsUnknownColumn(t *testing.T) {
+	sorter := NewSorter(io.Discard, Options{Format: CSV, Header: true, Keys: []Key{{Field: "missing"}}})
+	if _, err := sorter.Write([]byte("a,b\n1,2\n")); err == nil {
+		t.Errorf("expected error for unknown column")
+	}
+	sorter.Abort()
+}
+
+func TestRecordsWithHeaderAcrossInputs(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i, content := range []string{"n,v\nb,\"2\n\"\nd,4\n", "n,v\na,1\nc,3", "n,v\n"} {
+		name := filepath.Join(dir, fmt.Sprintf("input%d.csv", i))
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, name)
+	}
+	options := Options{Format: CSV, Header: true, Keys: []Key{{Field: "v", Order: Order{Numeric: true}}}, TempDir: t.TempDir()}
+	expected := "n,v\na,1\nb,\"2\n\"\nc,3\nd,4\n"
+
+	in := NewInputReader(names, options)
+	defer in.Close()
+	var sorted bytes.Buffer
+	sorter := NewSorter(&sorted, options)
+	if _, err := io.Copy(sorter, in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sorter.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sorted.String() != expected {
+		t.Errorf("sort: expected %q, got %q", expected, sorted.String())
+	}
+
+	var merged bytes.Buffer
+	if err := MergeFiles(&merged, options, names[0], names[1], names[2]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged.String() != expected {
+		t.Errorf("merge: expected %q, got %q", expected, merged.String())
+	}
+
+	lineNum, line, err := FindDisorder(strings.NewReader("n,v\n\"x\ny\",5\nz,4\n"), options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lineNum != 3 || line != "z,4" {
+		t.Errorf("FindDisorder = %d, %q; want 3, %q", lineNum, line, "z,4")
+	}
+}
+
+// bzip2Sample — "delta\nalpha\n", сжатое bzip2 (в стандартной библиотеке нет
+// упаковщика bzip2)
+var bzip2Sample = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xcf, 0x5e,
+	0x00, 0x28, 0x00, 0x00, 0x02, 0xc1, 0x80, 0x00, 0x10, 0x26, 0x44, 0x44,
+	0x00, 0x20, 0x00, 0x21, 0x28, 0x32, 0x68, 0x43, 0x02, 0x2c, 0x8f, 0x45,
+	0x1f, 0x43, 0xc5, 0xdc, 0x91, 0x4e, 0x14, 0x24, 0x33, 0xd7, 0x80, 0x0a,
+	0x00,
+}
+
+// gzipString сжимает s gzip
+func gzipString(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"plain", []byte("b\na\n"), "b\na\n"},
+		{"empty", nil, ""},
+		{"plain with bzip2 header", []byte("BZh9 is not compressed\n"), "BZh9 is not compressed\n"},
+		{"gzip", gzipString(t, "b\na\n"), "b\na\n"},
+		{"concatenated gzip", append(gzipString(t, "b\n"), gzipString(t, "a\n")...), "b\na\n"},
+		{"bzip2", bzip2Sample, "delta\nalpha\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := Decompress(bytes.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	zstd := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x11, 0x00, 0x00, 0x78, 0x0a, 0xae, 0xfd, 0xe9, 0x22}
+	if _, err := Decompress(bytes.NewReader(zstd)); !errors.Is(err, ErrZstd) {
+		t.Errorf("expected ErrZstd, got %v", err)
+	}
+}
+
+func TestMergeCompressedFiles(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i, content := range [][]byte{gzipString(t, "b\ne\n"), bzip2Sample, []byte("c\n")} {
+		name := filepath.Join(dir, fmt.Sprintf("input%d", i))
+		if err := os.WriteFile(name, content, 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, name)
+	}
+
+	// bzip2Sample не отсортирован, поэтому сливается только его порядок
+	var output bytes.Buffer
+	if err := MergeFiles(&output, Options{TempDir: t.TempDir()}, names...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "b\nc\ndelta\nalpha\ne\n"; output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+
+	in := NewInputReader(names, Options{})
+	defer in.Close()
+	var sorted bytes.Buffer
+	sorter := NewSorter(&sorted, Options{BufferSize: 1 << 20, TempDir: t.TempDir(), Parallel: 1})
+	if _, err := io.Copy(sorter, in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sorter.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "alpha\nb\nc\ndelta\ne\n"; sorted.String() != expected {
+		t.Errorf("expected %q, got %q", expected, sorted.String())
+	}
+}
@@ -16,207 +16,252 @@ package main
     -b — игнорировать хвостовые пробелы;
     -c — проверять отсортированы ли данные;
     -h — сортировать по числовому значению с учетом суффиксов.
+
+Входы, сжатые gzip или bzip2, распаковываются автоматически; --gzip
+сжимает результат.
 */
 
 import (
-	"bufio"
+	"compress/gzip"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
+	"time"
+
+	"gosort/sortlib"
 )
 
-// SortOptions содержит флаги сортировки
-type SortOptions struct {
-	column       int
-	numeric      bool
-	reverse      bool
-	unique       bool
-	month        bool
-	ignoreSpaces bool
-	checkSorted  bool
-	humanNumeric bool
+// keyList собирает повторяющиеся флаги -k; разбираются они после
+// всех флагов, когда известен формат записей
+type keyList []string
+
+func (k *keyList) String() string {
+	return fmt.Sprint(*k)
+}
+
+func (k *keyList) Set(value string) error {
+	*k = append(*k, value)
+	return nil
+}
+
+// parseKeys разбирает описания ключей: для строк — колонки sort,
+// для CSV, TSV и JSONL — поля записей
+func parseKeys(specs []string, format sortlib.Format) ([]sortlib.Key, error) {
+	parse := sortlib.ParseKey
+	if format != sortlib.Lines {
+		parse = sortlib.ParseFieldKey
+	}
+	keys := make([]sortlib.Key, 0, len(specs))
+	for _, spec := range specs {
+		key, err := parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		if format == sortlib.JSONL && key.Field == "" {
+			return nil, fmt.Errorf("jsonl key must be a path: %q", spec)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
 }
 
 func main() {
 	// Парсинг аргументов
-	column := flag.Int("k", 0, "колонка для сортировки (начиная с 1)")
+	var keys keyList
+	flag.Var(&keys, "k", "ключ сортировки: колонки с первой по последнюю с буквами порядка, например 2 (до конца строки) или 2,3n (начиная с 1); для --format — поле, например price:n или user.age:nr")
 	numeric := flag.Bool("n", false, "сортировка по числовому значению")
 	reverse := flag.Bool("r", false, "обратный порядок сортировки")
 	unique := flag.Bool("u", false, "удаление повторяющихся строк")
 	month := flag.Bool("M", false, "сортировка по названию месяца")
-	ignoreSpaces := flag.Bool("b", false, "игнорировать хвостовые пробелы")
-	checkSorted := flag.Bool("c", false, "проверить, отсортированы ли данные")
+	ignoreSpaces := flag.Bool("b", false, "игнорировать начальные пробелы в ключах")
+	checkSorted := flag.Bool("c", false, "проверить, отсортированы ли данные, и сообщить о первом беспорядке")
+	quietCheck := flag.Bool("C", false, "как -c, но без сообщения о беспорядке")
 	humanNumeric := flag.Bool("h", false, "сортировка с учетом числовых суффиксов")
+	general := flag.Bool("g", false, "сортировка по общему числовому значению (экспоненты, inf, nan)")
+	version := flag.Bool("V", false, "естественная сортировка номеров версий")
+	random := flag.Bool("R", false, "случайный порядок с группировкой одинаковых ключей")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "зерно для -R")
+	stable := flag.Bool("s", false, "устойчивая сортировка")
+	foldCase := flag.Bool("f", false, "не различать регистр")
+	dictionary := flag.Bool("d", false, "учитывать только буквы, цифры и пробелы")
+	ignoreNonPrinting := flag.Bool("i", false, "пропускать непечатаемые символы")
+	locale := flag.String("locale", "", "локаль сопоставления по Unicode Collation Algorithm, например ru или en_US.UTF-8 (по умолчанию побайтово)")
+	normalization := flag.String("normalize", "", "нормализация Unicode ключей: nfc или nfd")
+	merge := flag.Bool("m", false, "слить уже отсортированные файлы без пересортировки")
+	zeroEnded := flag.Bool("z", false, "записи завершаются NUL, а не переводом строки")
+	outputFile := flag.String("o", "", "записать результат в файл (может совпадать с входным)")
+	bufferSize := flag.String("S", sortlib.DefaultBufferSize, "бюджет памяти (суффиксы b, K, M, G, T; по умолчанию K)")
+	tempDir := flag.String("T", os.TempDir(), "каталог для временных файлов")
+	parallel := flag.Int("parallel", sortlib.DefaultParallel(), "число блоков, сортируемых одновременно")
+	formatName := flag.String("format", "lines", "формат записей: lines, csv, tsv или jsonl")
+	header := flag.Bool("header", false, "первая запись — заголовок: остается первой и задает имена колонок")
+	compress := flag.Bool("gzip", false, "сжимать результат gzip")
 
 	flag.Parse()
 
-	if len(flag.Args()) < 1 {
-		fmt.Println("Usage: sort [OPTIONS] <input-file> [output-file]")
-		os.Exit(1)
+	// Без файлов или с "-" читается стандартный ввод
+	inputFiles := flag.Args()
+	if len(inputFiles) == 0 {
+		inputFiles = []string{sortlib.StdinName}
 	}
 
-	inputFile := flag.Arg(0)
-	outputFile := ""
-	if len(flag.Args()) > 1 {
-		outputFile = flag.Arg(1)
+	budget, err := sortlib.ParseSize(*bufferSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -S: %v\n", err)
+		os.Exit(1)
 	}
-
-	options := SortOptions{
-		column:       *column - 1,
-		numeric:      *numeric,
-		reverse:      *reverse,
-		unique:       *unique,
-		month:        *month,
-		ignoreSpaces: *ignoreSpaces,
-		checkSorted:  *checkSorted,
-		humanNumeric: *humanNumeric,
+	if *parallel < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --parallel must be positive")
+		os.Exit(1)
 	}
-
-	lines, err := readLines(inputFile)
+	if err := sortlib.ParseLocale(*locale); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing --locale: %v\n", err)
+		os.Exit(1)
+	}
+	form, err := sortlib.ParseNormalization(*normalization)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error parsing --normalize: %v\n", err)
 		os.Exit(1)
 	}
 
-	if options.checkSorted {
-		if isSorted(lines, options) {
-			fmt.Println("File is sorted.")
-		} else {
-			fmt.Println("File is not sorted.")
-		}
-		os.Exit(0)
+	format, err := sortlib.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing --format: %v\n", err)
+		os.Exit(1)
 	}
-
-	sortedLines, err := sortLines(lines, options)
+	sortKeys, err := parseKeys(keys, format)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error during sorting: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error parsing -k: %v\n", err)
 		os.Exit(1)
 	}
-
-	if outputFile == "" {
-		for _, line := range sortedLines {
-			fmt.Println(line)
-		}
-	} else {
-		if err := writeLines(outputFile, sortedLines); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
+	for _, key := range sortKeys {
+		if key.Field != "" && format != sortlib.JSONL && !*header {
+			fmt.Fprintln(os.Stderr, "Error: column names in -k require --header")
 			os.Exit(1)
 		}
 	}
-}
-
-// readLines читает строки из файла
-func readLines(fileName string) ([]string, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	options := sortlib.Options{
+		Order: sortlib.Order{
+			Numeric:           *numeric,
+			Month:             *month,
+			HumanNumeric:      *humanNumeric,
+			General:           *general,
+			Version:           *version,
+			Random:            *random,
+			IgnoreBlanks:      *ignoreSpaces,
+			Reverse:           *reverse,
+			FoldCase:          *foldCase,
+			Dictionary:        *dictionary,
+			IgnoreNonPrinting: *ignoreNonPrinting,
+		},
+		Keys:           sortKeys,
+		Format:         format,
+		Header:         *header,
+		Unique:         *unique,
+		Stable:         *stable,
+		Seed:           *seed,
+		Locales:        sortlib.MonthLocales(),
+		Locale:         *locale,
+		Normalization:  form,
+		ZeroTerminated: *zeroEnded,
+		BufferSize:     budget,
+		TempDir:        *tempDir,
+		Parallel:       *parallel,
 	}
-	return lines, scanner.Err()
-}
 
-// writeLines записывает строки в файл
-func writeLines(fileName string, lines []string) error {
-	file, err := os.Create(fileName)
-	if err != nil {
-		return err
+	if *checkSorted || *quietCheck {
+		if len(inputFiles) > 1 {
+			fmt.Fprintln(os.Stderr, "Error: -c accepts only one input file")
+			os.Exit(2)
+		}
+		os.Exit(checkFile(inputFiles[0], *quietCheck, options))
 	}
-	defer file.Close()
 
-	writer := bufio.NewWriter(file)
-	for _, line := range lines {
-		_, err := writer.WriteString(line + "\n")
-		if err != nil {
-			return err
-		}
+	if err := sortFiles(inputFiles, *outputFile, *merge, *compress, options); err != nil {
+		fmt.Fprintf(os.Stderr, "Error during sorting: %v\n", err)
+		os.Exit(1)
 	}
-	return writer.Flush()
 }
 
-// sortLines сортирует строки с учетом заданных опций
-func sortLines(lines []string, options SortOptions) ([]string, error) {
-	if options.ignoreSpaces {
-		for i, line := range lines {
-			lines[i] = strings.TrimRight(line, " ")
-		}
+// sortFiles сортирует (или с merge сливает) входы inputFiles и пишет результат
+// в outputFile или в stdout, если outputFile пуст; с compress результат сжимается gzip
+func sortFiles(inputFiles []string, outputFile string, merge, compress bool, options sortlib.Options) (err error) {
+	var out io.Writer = os.Stdout
+	if outputFile != "" {
+		output := &lazyOutput{name: outputFile}
+		defer func() {
+			// При ошибке файл результата не создается, чтобы не затереть
+			// совпадающий с ним вход
+			if err != nil {
+				if output.file != nil {
+					output.file.Close()
+				}
+				return
+			}
+			err = output.Close()
+		}()
+		out = output
 	}
-
-	if options.unique {
-		lines = unique(lines)
+	if compress {
+		gzipOut := gzip.NewWriter(out)
+		defer func() {
+			// При ошибке концовка потока не пишется, иначе она создала бы файл результата
+			if err == nil {
+				err = gzipOut.Close()
+			}
+		}()
+		out = gzipOut
 	}
 
-	sort.Slice(lines, func(i, j int) bool {
-		left, right := lines[i], lines[j]
-
-		if options.column >= 0 {
-			leftParts := strings.Fields(left)
-			rightParts := strings.Fields(right)
-			if len(leftParts) > options.column {
-				left = leftParts[options.column]
-			} else {
-				left = ""
-			}
-			if len(rightParts) > options.column {
-				right = rightParts[options.column]
-			} else {
-				right = ""
+	if merge {
+		if outputFile != "" {
+			dir, err := os.MkdirTemp(options.TempDir, "sort")
+			if err != nil {
+				return err
 			}
-		}
+			defer os.RemoveAll(dir)
 
-		if options.numeric {
-			leftNum, err1 := strconv.ParseFloat(left, 64)
-			rightNum, err2 := strconv.ParseFloat(right, 64)
-			if err1 == nil && err2 == nil {
-				if options.reverse {
-					return leftNum > rightNum
-				}
-				return leftNum < rightNum
+			if inputFiles, err = protectInputs(inputFiles, outputFile, dir); err != nil {
+				return err
 			}
 		}
+		return sortlib.MergeFiles(out, options, inputFiles...)
+	}
 
-		if options.reverse {
-			return left > right
-		}
-		return left < right
-	})
+	in := sortlib.NewInputReader(inputFiles, options)
+	defer in.Close()
 
-	return lines, nil
+	sorter := sortlib.NewSorter(out, options)
+	if _, err := io.Copy(sorter, in); err != nil {
+		sorter.Abort()
+		return err
+	}
+	return sorter.Close()
 }
 
-// unique удаляет повторяющиеся строки
-func unique(lines []string) []string {
-	seen := make(map[string]struct{})
-	var result []string
-	for _, line := range lines {
-		if _, exists := seen[line]; !exists {
-			result = append(result, line)
-			seen[line] = struct{}{}
-		}
+// checkFile проверяет, отсортирован ли файл, и возвращает код выхода:
+// 0 — отсортирован, 1 — найден беспорядок (о нем сообщается, если не задан quiet),
+// 2 — ошибка чтения
+func checkFile(inputFile string, quiet bool, options sortlib.Options) int {
+	file, err := sortlib.OpenInput(inputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+		return 2
 	}
-	return result
-}
+	defer file.Close()
 
-// isSorted проверяет, отсортированы ли строки
-func isSorted(lines []string, options SortOptions) bool {
-	for i := 1; i < len(lines); i++ {
-		if options.reverse {
-			if lines[i-1] < lines[i] {
-				return false
-			}
-		} else {
-			if lines[i-1] > lines[i] {
-				return false
-			}
-		}
+	lineNum, line, err := sortlib.FindDisorder(file, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+		return 2
+	}
+	if lineNum == 0 {
+		return 0
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "sort: %s:%d: disorder: %s\n", inputFile, lineNum, line)
 	}
-	return true
+	return 1
 }
@@ -1 +1,200 @@
 package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gosort/sortlib"
+)
+
+// writeFiles создает во временном каталоге файлы с заданным содержимым
+func writeFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	names := make([]string, len(contents))
+	for i, content := range contents {
+		names[i] = filepath.Join(dir, fmt.Sprintf("input%d.txt", i))
+		if err := os.WriteFile(names[i], []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return names
+}
+
+func TestSortFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []string
+		merge    bool
+		options  sortlib.Options
+		expected string
+	}{
+		{
+			name:     "several files without trailing newline",
+			contents: []string{"c\na", "d\nb\n", "e"},
+			options:  sortlib.Options{},
+			expected: "a\nb\nc\nd\ne\n",
+		},
+		{
+			name:     "merge presorted",
+			contents: []string{"a\nc\ne\n", "b\nd", "a\nf\n"},
+			merge:    true,
+			options:  sortlib.Options{},
+			expected: "a\na\nb\nc\nd\ne\nf\n",
+		},
+		{
+			name:     "merge unique numeric",
+			contents: []string{"1\n5\n10\n", "2\n5\n20\n"},
+			merge:    true,
+			options:  sortlib.Options{Order: sortlib.Order{Numeric: true}, Unique: true},
+			expected: "1\n2\n5\n10\n20\n",
+		},
+		{
+			name:     "zero terminated",
+			contents: []string{"b\nx\x00a\x00", "c y\x00"},
+			options:  sortlib.Options{ZeroTerminated: true},
+			expected: "a\x00b\nx\x00c y\x00",
+		},
+		{
+			name:     "csv files with headers",
+			contents: []string{"id,name\n3,\"c\nc\"\n1,a\n", "id,name\n2,b"},
+			options: sortlib.Options{
+				Format: sortlib.CSV,
+				Header: true,
+				Keys:   []sortlib.Key{{Field: "id", Order: sortlib.Order{Numeric: true}}},
+			},
+			expected: "id,name\n1,a\n2,b\n3,\"c\nc\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := writeFiles(t, tt.contents...)
+			output := filepath.Join(t.TempDir(), "out.txt")
+			options := tt.options
+			options.BufferSize, options.TempDir, options.Parallel = 1<<20, t.TempDir(), 1
+
+			if err := sortFiles(names, output, tt.merge, false, options); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := parseKeys([]string{"2,3n", "1"}, sortlib.Lines)
+	if err != nil || len(keys) != 2 || keys[0].End != 3 || !keys[0].Order.Numeric {
+		t.Errorf("unexpected line keys %+v, error %v", keys, err)
+	}
+	keys, err = parseKeys([]string{"price:nr", "2"}, sortlib.CSV)
+	if err != nil || len(keys) != 2 || keys[0].Field != "price" || keys[1].Start != 2 {
+		t.Errorf("unexpected csv keys %+v, error %v", keys, err)
+	}
+	if _, err := parseKeys([]string{"2"}, sortlib.JSONL); err == nil {
+		t.Errorf("expected error for jsonl key without path")
+	}
+}
+
+func TestSortFilesOutputIsInput(t *testing.T) {
+	for _, merge := range []bool{false, true} {
+		names := writeFiles(t, "a\nc\n", "b\nd\n")
+		options := sortlib.Options{BufferSize: 4, TempDir: t.TempDir(), Parallel: 1}
+
+		if err := sortFiles(names, names[0], merge, false, options); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := os.ReadFile(names[0])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := "a\nb\nc\nd\n"; string(result) != expected {
+			t.Errorf("merge=%v: expected %q, got %q", merge, expected, result)
+		}
+	}
+}
+
+func TestSortFilesKeepsOutputOnError(t *testing.T) {
+	names := writeFiles(t, "b\na\n")
+	options := sortlib.Options{BufferSize: 1 << 20, TempDir: t.TempDir(), Parallel: 1}
+
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	if err := sortFiles([]string{names[0], missing}, names[0], false, false, options); err == nil {
+		t.Fatalf("expected an error for a missing input")
+	}
+	result, err := os.ReadFile(names[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "b\na\n"; string(result) != expected {
+		t.Errorf("expected input to stay %q, got %q", expected, result)
+	}
+}
+
+func TestSortFilesStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		w.WriteString("z\ny\n")
+		w.Close()
+	}()
+
+	names := writeFiles(t, "x\n")
+	output := filepath.Join(t.TempDir(), "out.txt")
+	options := sortlib.Options{BufferSize: 1 << 20, TempDir: t.TempDir(), Parallel: 1}
+	if err := sortFiles([]string{sortlib.StdinName, names[0]}, output, false, false, options); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "x\ny\nz\n"; string(result) != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestSortFilesCompressed(t *testing.T) {
+	var input bytes.Buffer
+	w := gzip.NewWriter(&input)
+	w.Write([]byte("c\na\n"))
+	w.Close()
+	names := writeFiles(t, input.String(), "b\n")
+
+	output := filepath.Join(t.TempDir(), "out.gz")
+	options := sortlib.Options{BufferSize: 1 << 20, TempDir: t.TempDir(), Parallel: 1}
+	if err := sortFiles(names, output, false, true, options); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Сжатый результат читается обратно тем же путем, что и входы
+	file, err := sortlib.OpenInput(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	result, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "a\nb\nc\n"; string(result) != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule — одно правило .gitignore
+type ignoreRule struct {
+	base     string // каталог файла .gitignore относительно корня обхода, через "/"
+	pattern  string
+	negate   bool // правило "!шаблон" возвращает ранее исключенный путь
+	dirOnly  bool // шаблон с завершающим "/" относится только к каталогам
+	anchored bool // шаблон со "/" сопоставляется с путем от base, а не с именем
+}
+
+// ignoreRules — правила .gitignore в порядке действия: более поздние
+// (в том числе из вложенных каталогов) отменяют более ранние
+type ignoreRules []ignoreRule
+
+// loadIgnoreFile добавляет к rules правила файла .gitignore каталога dir;
+// base — путь dir относительно корня обхода. Отсутствующий файл не ошибка.
+func loadIgnoreFile(rules ignoreRules, dir, base string) ignoreRules {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return rules
+	}
+	defer file.Close()
+
+	// Срез копируется, чтобы правила подкаталога не попали к соседним каталогам
+	rules = append(ignoreRules(nil), rules...)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate, line = true, line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored, line = true, strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored сообщает, исключен ли путь rel (относительно корня обхода, через "/")
+func (rules ignoreRules) ignored(rel string, isDir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			name = rel[len(rule.base)+1:]
+		}
+		if !rule.anchored {
+			name = path.Base(name)
+		}
+		if matchGlob(rule.pattern, name) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
+
+// matchGlob сопоставляет путь с шаблоном, где "**" означает любое число
+// каталогов, а остальные сегменты сравниваются по правилам path.Match
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,222 @@
+package matcher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// jsonDuration — время в формате ripgrep: секунды, наносекунды и строка для человека
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	duration := time.Duration(d)
+	return json.Marshal(struct {
+		Secs  int64  `json:"secs"`
+		Nanos int64  `json:"nanos"`
+		Human string `json:"human"`
+	}{
+		Secs:  int64(duration / time.Second),
+		Nanos: int64(duration % time.Second),
+		Human: fmt.Sprintf("%.6fs", duration.Seconds()),
+	})
+}
+
+// jsonStats — статистика поиска в событиях end и summary
+type jsonStats struct {
+	Elapsed           jsonDuration `json:"elapsed"`
+	Searches          int          `json:"searches"`
+	SearchesWithMatch int          `json:"searches_with_match"`
+	BytesSearched     int64        `json:"bytes_searched"`
+	BytesPrinted      int64        `json:"bytes_printed"`
+	MatchedLines      int          `json:"matched_lines"`
+	Matches           int          `json:"matches"`
+}
+
+func (s *jsonStats) add(other jsonStats) {
+	s.Elapsed += other.Elapsed
+	s.Searches += other.Searches
+	s.SearchesWithMatch += other.SearchesWithMatch
+	s.BytesSearched += other.BytesSearched
+	s.BytesPrinted += other.BytesPrinted
+	s.MatchedLines += other.MatchedLines
+	s.Matches += other.Matches
+}
+
+// jsonEvent — строка вывода --json
+type jsonEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// jsonLine — данные событий match и context
+type jsonLine struct {
+	Path           map[string]string `json:"path"`
+	Lines          map[string]string `json:"lines"`
+	LineNumber     int               `json:"line_number"`
+	AbsoluteOffset int64             `json:"absolute_offset"`
+	Submatches     []jsonSubmatch    `json:"submatches"`
+}
+
+type jsonSubmatch struct {
+	Match map[string]string `json:"match"`
+	Start int               `json:"start"`
+	End   int               `json:"end"`
+}
+
+// jsonData представляет строку как {"text": ...}, а не UTF-8 — как {"bytes": base64}
+func jsonData(s string) map[string]string {
+	if utf8.ValidString(s) {
+		return map[string]string{"text": s}
+	}
+	return map[string]string{"bytes": base64.StdEncoding.EncodeToString([]byte(s))}
+}
+
+// JSONStats накапливает статистику всех входов для итогового события
+// summary. Методы можно вызывать из нескольких горутин.
+type JSONStats struct {
+	start time.Time
+	mu    sync.Mutex
+	total jsonStats
+}
+
+// NewJSONStats создает JSONStats; общее время отсчитывается от вызова
+func NewJSONStats() *JSONStats {
+	return &JSONStats{start: time.Now()}
+}
+
+// Printer создает JSONPrinter для входа name, статистика которого
+// добавляется к общей по окончании поиска
+func (s *JSONStats) Printer(w io.Writer, name string) *JSONPrinter {
+	return &JSONPrinter{w: w, name: name, start: time.Now(), collector: s}
+}
+
+// WriteSummary пишет в w событие summary с общей статистикой
+func (s *JSONStats) WriteSummary(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeEvent(w, "summary", struct {
+		ElapsedTotal jsonDuration `json:"elapsed_total"`
+		Stats        jsonStats    `json:"stats"`
+	}{jsonDuration(time.Since(s.start)), s.total})
+}
+
+// JSONPrinter печатает результаты поиска событиями JSON по одному в строке,
+// в духе ripgrep --json: begin перед первой строкой входа, match и context
+// для строк и end со статистикой входа. Входы без совпадений не печатаются.
+// Текст строк в событиях завершается переводом строки.
+type JSONPrinter struct {
+	w         io.Writer
+	name      string
+	start     time.Time
+	begun     bool
+	stats     jsonStats
+	collector *JSONStats
+}
+
+func (p *JSONPrinter) Print(record Record) error {
+	if err := p.begin(); err != nil {
+		return err
+	}
+
+	kind := "context"
+	submatches := []jsonSubmatch{}
+	if record.Kind == Match {
+		kind = "match"
+		for _, span := range record.Spans {
+			submatches = append(submatches, jsonSubmatch{
+				Match: jsonData(record.Text[span.Start:span.End]),
+				Start: span.Start,
+				End:   span.End,
+			})
+		}
+		p.stats.Matches += max(len(record.Spans), 1)
+	}
+	return p.write(kind, jsonLine{
+		Path:           jsonData(p.name),
+		Lines:          jsonData(record.Text + "\n"),
+		LineNumber:     record.Line,
+		AbsoluteOffset: record.Offset,
+		Submatches:     submatches,
+	})
+}
+
+func (p *JSONPrinter) Finish(summary Summary) error {
+	if summary.Skipped {
+		return nil
+	}
+	p.stats.Elapsed = jsonDuration(time.Since(p.start))
+	p.stats.Searches = 1
+	p.stats.BytesSearched = summary.Bytes
+	p.stats.MatchedLines = summary.Matches
+	if summary.Matches > 0 {
+		p.stats.SearchesWithMatch = 1
+	}
+	if summary.Binary {
+		// Строки двоичного входа не передаются, известно лишь число совпавших
+		p.stats.Matches = summary.Matches
+	}
+
+	// bytes_printed — размер событий входа до end: само событие end,
+	// содержащее статистику, в ней не учитывается ни для входа, ни в summary
+	var err error
+	if p.begun || summary.Matches > 0 {
+		if err = p.begin(); err == nil {
+			err = writeEvent(p.w, "end", struct {
+				Path  map[string]string `json:"path"`
+				Stats jsonStats         `json:"stats"`
+			}{jsonData(p.name), p.stats})
+		}
+	}
+	if p.collector != nil {
+		p.collector.mu.Lock()
+		p.collector.total.add(p.stats)
+		p.collector.mu.Unlock()
+	}
+	return err
+}
+
+// begin печатает событие begin, если оно еще не напечатано
+func (p *JSONPrinter) begin() error {
+	if p.begun {
+		return nil
+	}
+	p.begun = true
+	return p.write("begin", struct {
+		Path map[string]string `json:"path"`
+	}{jsonData(p.name)})
+}
+
+// write печатает событие и учитывает его размер в статистике
+func (p *JSONPrinter) write(kind string, data any) error {
+	counter := &countingWriter{w: p.w}
+	err := writeEvent(counter, kind, data)
+	p.stats.BytesPrinted += counter.n
+	return err
+}
+
+// writeEvent пишет событие одной строкой за один вызов Write
+func writeEvent(w io.Writer, kind string, data any) error {
+	line, err := json.Marshal(jsonEvent{Type: kind, Data: data})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// countingWriter считает записанные байты
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,168 @@
+package matcher
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// literalMatcher ищет фиксированные строки без регулярных выражений;
+// с fold регистр не различается
+type literalMatcher struct {
+	patterns []string
+	fold     bool
+	word     bool
+	line     bool
+}
+
+// NewFixed создает Matcher для фиксированных строк с учетом регистра
+func NewFixed(patterns []string, options Options) Matcher {
+	return &literalMatcher{patterns: patterns, word: options.Word, line: options.Line}
+}
+
+// NewFolded создает Matcher для фиксированных строк без учета регистра:
+// символы сравниваются по простой свертке регистра Unicode, поэтому
+// границы совпадений указывают на исходные байты строки
+func NewFolded(patterns []string, options Options) Matcher {
+	return &literalMatcher{patterns: patterns, fold: true, word: options.Word, line: options.Line}
+}
+
+func (m *literalMatcher) Match(line string) bool {
+	_, _, ok := m.find(line, 0)
+	return ok
+}
+
+func (m *literalMatcher) Spans(line string) []Span {
+	var spans []Span
+	for pos := 0; pos <= len(line); {
+		start, end, ok := m.find(line, pos)
+		if !ok {
+			break
+		}
+		if start < end {
+			spans = append(spans, Span{start, end})
+			pos = end
+			continue
+		}
+		if start == len(line) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(line[start:])
+		pos = start + size
+	}
+	return spans
+}
+
+// find возвращает первое совпадение, начинающееся не раньше from и
+// удовлетворяющее ограничениям word и line; при нескольких шаблонах,
+// совпадающих с одной позиции, выбирается самый длинный
+func (m *literalMatcher) find(line string, from int) (int, int, bool) {
+	if m.line {
+		for _, pattern := range m.patterns {
+			if m.prefix(line, pattern) == len(line) {
+				return 0, len(line), true
+			}
+		}
+		return 0, 0, false
+	}
+
+	for from <= len(line) {
+		start := -1
+		for _, pattern := range m.patterns {
+			if i := m.index(line[from:], pattern); i >= 0 && (start < 0 || from+i < start) {
+				start = from + i
+			}
+		}
+		if start < 0 {
+			return 0, 0, false
+		}
+
+		end := -1
+		for _, pattern := range m.patterns {
+			n := m.prefix(line[start:], pattern)
+			if n >= 0 && start+n > end && (!m.word || isWord(line, start, start+n)) {
+				end = start + n
+			}
+		}
+		if end >= 0 {
+			return start, end, true
+		}
+		// Совпадение не является словом: ищем дальше
+		if start == len(line) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(line[start:])
+		from = start + size
+	}
+	return 0, 0, false
+}
+
+// index возвращает позицию первого вхождения pattern в s или -1
+func (m *literalMatcher) index(s, pattern string) int {
+	if !m.fold {
+		return strings.Index(s, pattern)
+	}
+	for i := 0; i <= len(s); {
+		if m.prefix(s[i:], pattern) >= 0 {
+			return i
+		}
+		if i == len(s) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return -1
+}
+
+// prefix возвращает длину в байтах начала s, совпадающего с pattern, или -1
+func (m *literalMatcher) prefix(s, pattern string) int {
+	if !m.fold {
+		if strings.HasPrefix(s, pattern) {
+			return len(pattern)
+		}
+		return -1
+	}
+	i := 0
+	for _, p := range pattern {
+		if i >= len(s) {
+			return -1
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !equalFold(r, p) {
+			return -1
+		}
+		i += size
+	}
+	return i
+}
+
+// equalFold сообщает, равны ли символы с точностью до свертки регистра
+func equalFold(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for f := unicode.SimpleFold(a); f != a; f = unicode.SimpleFold(f) {
+		if f == b {
+			return true
+		}
+	}
+	return false
+}
+
+// isWord сообщает, окружено ли совпадение line[start:end] символами,
+// не входящими в слова (или границами строки)
+func isWord(line string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(line[:start]); start > 0 && isWordChar(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isWordChar(after) {
+		return false
+	}
+	return true
+}
+
+// isWordChar сообщает, входит ли символ в слово: буквы, цифры и "_"
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+}
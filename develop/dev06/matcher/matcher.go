@@ -0,0 +1,280 @@
+// Package matcher реализует поиск строк по шаблонам с семантикой grep:
+// сопоставители фиксированных строк и регулярных выражений (BRE, ERE),
+// потоковый Searcher с контекстом и печать результатов.
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// wordChars — символы, из которых состоит слово для Options.Word
+const wordChars = `\p{L}\p{N}_`
+
+// Syntax задает синтаксис шаблонов
+type Syntax int
+
+const (
+	// Basic — базовые регулярные выражения POSIX (grep -G)
+	Basic Syntax = iota
+	// Extended — расширенные регулярные выражения POSIX (grep -E)
+	Extended
+	// Fixed — фиксированные строки (grep -F)
+	Fixed
+)
+
+// Options задает способ сопоставления
+type Options struct {
+	Syntax     Syntax
+	IgnoreCase bool // -i
+	Word       bool // -w, совпадение только с целыми словами
+	Line       bool // -x, совпадение только со всей строкой
+}
+
+// Span — границы совпадения в строке в байтах
+type Span struct {
+	Start, End int
+}
+
+// Matcher ищет в строке совпадения с набором шаблонов; строка совпадает,
+// если совпадает хотя бы один шаблон
+type Matcher interface {
+	// Match сообщает, есть ли в строке совпадение
+	Match(line string) bool
+	// Spans возвращает границы непересекающихся непустых совпадений слева направо
+	Spans(line string) []Span
+}
+
+// New создает Matcher для шаблонов: фиксированные строки ищутся напрямую
+// (с IgnoreCase — со сверткой регистра), регулярные выражения — через RE2
+func New(patterns []string, options Options) (Matcher, error) {
+	if options.Syntax == Fixed {
+		if options.IgnoreCase {
+			return NewFolded(patterns, options), nil
+		}
+		return NewFixed(patterns, options), nil
+	}
+	return NewRegex(patterns, options)
+}
+
+// regexMatcher сопоставляет строки с регулярным выражением. Совпадение
+// шаблонов — первая группа выражения: с Word и Line вокруг нее стоят границы.
+type regexMatcher struct {
+	re *regexp.Regexp
+	// next с Word ищет следующее слово, начиная с символа перед ним:
+	// в отличие от re в нем нет альтернативы "^"
+	next *regexp.Regexp
+}
+
+// NewRegex компилирует шаблоны в одно регулярное выражение RE2. Шаблоны
+// трактуются как BRE, ERE или, с Fixed, как фиксированные строки.
+func NewRegex(patterns []string, options Options) (Matcher, error) {
+	alternatives := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		if options.Syntax == Fixed {
+			alternatives[i] = regexp.QuoteMeta(pattern)
+			continue
+		}
+		translated, err := translatePattern(pattern, options.Syntax == Extended)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		alternatives[i] = translated
+	}
+
+	core := "((?:" + strings.Join(alternatives, ")|(?:") + "))"
+	if len(patterns) == 0 {
+		// Пустой список шаблонов (например, пустой файл -f) не совпадает ни с чем
+		core = `([^\x00-\x{10FFFF}])`
+	}
+	flags := ""
+	if options.IgnoreCase {
+		flags = "(?i)"
+	}
+
+	var m regexMatcher
+	var err error
+	switch {
+	case options.Line:
+		m.re, err = regexp.Compile(flags + "^" + core + "$")
+	case options.Word:
+		after := "(?:[^" + wordChars + "]|$)"
+		if m.re, err = regexp.Compile(flags + "(?:^|[^" + wordChars + "])" + core + after); err == nil {
+			m.next, err = regexp.Compile(flags + "[^" + wordChars + "]" + core + after)
+		}
+	default:
+		m.re, err = regexp.Compile(flags + core)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (m *regexMatcher) Match(line string) bool {
+	return m.re.MatchString(line)
+}
+
+func (m *regexMatcher) Spans(line string) []Span {
+	var spans []Span
+	if m.next == nil {
+		for _, loc := range m.re.FindAllStringSubmatchIndex(line, -1) {
+			if loc[2] < loc[3] {
+				spans = append(spans, Span{loc[2], loc[3]})
+			}
+		}
+		return spans
+	}
+
+	// Границы слов поглощают соседние символы, поэтому каждое следующее
+	// слово ищется с последнего символа предыдущего совпадения
+	offset := 0
+	loc := m.re.FindStringSubmatchIndex(line)
+	for loc != nil {
+		start, end := offset+loc[2], offset+loc[3]
+		if start < end {
+			spans = append(spans, Span{start, end})
+		} else {
+			// Пустое совпадение: следующий поиск начинается со следующего символа
+			if end == len(line) {
+				break
+			}
+			_, size := utf8.DecodeRuneInString(line[end:])
+			end += size
+		}
+		_, size := utf8.DecodeLastRuneInString(line[:end])
+		offset = end - size
+		loc = m.next.FindStringSubmatchIndex(line[offset:])
+	}
+	return spans
+}
+
+// translatePattern переводит шаблон POSIX (BRE или ERE с расширениями GNU)
+// в синтаксис RE2. В BRE операторы (, ), {, }, |, + и ? пишутся
+// с обратной косой чертой, а без нее означают сами себя; ^ и $ — якоря
+// только в начале и в конце шаблона или группы.
+func translatePattern(pattern string, extended bool) (string, error) {
+	var b strings.Builder
+	runes := []rune(pattern)
+	// atStart — позиция, где * означает сам себя, а ^ в BRE — якорь
+	atStart := true
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '[':
+			end, class := translateBracket(runes, i)
+			if end < 0 {
+				return "", errors.New("unmatched [")
+			}
+			b.WriteString(class)
+			i = end
+			atStart = false
+			continue
+
+		case r == '\\':
+			if i+1 == len(runes) {
+				return "", errors.New("trailing backslash")
+			}
+			i++
+			next := runes[i]
+			switch {
+			case !extended && strings.ContainsRune("(){}|+?", next):
+				b.WriteRune(next)
+				atStart = next == '(' || next == '|'
+				continue
+			case next == '<' || next == '>':
+				b.WriteString(`\b`)
+			case strings.ContainsRune("bBwWsS", next):
+				b.WriteRune('\\')
+				b.WriteRune(next)
+			case next >= '1' && next <= '9':
+				return "", errors.New("back-references are not supported")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(next)))
+			}
+
+		case r == '*' && atStart:
+			b.WriteString(`\*`)
+
+		case !extended && strings.ContainsRune("(){}|+?", r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+
+		case !extended && r == '^' && !atStart:
+			b.WriteString(`\^`)
+
+		case !extended && r == '$' && !atBREEnd(runes, i):
+			b.WriteString(`\$`)
+
+		case extended && (r == '(' || r == '|'):
+			b.WriteRune(r)
+			atStart = true
+			continue
+
+		default:
+			b.WriteRune(r)
+			// После якоря ^ звездочка по-прежнему означает саму себя
+			atStart = atStart && r == '^'
+			continue
+		}
+		atStart = false
+	}
+	return b.String(), nil
+}
+
+// atBREEnd сообщает, стоит ли $ в позиции i в конце шаблона BRE или группы
+func atBREEnd(runes []rune, i int) bool {
+	rest := string(runes[i+1:])
+	return rest == "" || strings.HasPrefix(rest, `\)`) || strings.HasPrefix(rest, `\|`)
+}
+
+// translateBracket переводит выражение в квадратных скобках, начинающееся
+// в позиции start, и возвращает позицию закрывающей скобки (-1, если ее нет).
+// В POSIX обратная косая черта внутри скобок означает саму себя, а ']'
+// сразу после '[' или '[^' — часть набора.
+func translateBracket(runes []rune, start int) (int, string) {
+	var b strings.Builder
+	b.WriteRune('[')
+	i := start + 1
+	if i < len(runes) && runes[i] == '^' {
+		b.WriteRune('^')
+		i++
+	}
+	if i < len(runes) && runes[i] == ']' {
+		b.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == ']':
+			b.WriteRune(']')
+			return i, b.String()
+		case r == '[' && i+1 < len(runes) && strings.ContainsRune(":.=", runes[i+1]):
+			// Класс [:alpha:] и подобные копируются до закрывающих ":]"
+			end := classEnd(runes, i+2, runes[i+1])
+			if end < 0 {
+				return -1, ""
+			}
+			b.WriteString(string(runes[i : end+1]))
+			i = end
+		case r == '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return -1, ""
+}
+
+// classEnd возвращает позицию ']' в закрывающей паре delim + ']' или -1
+func classEnd(runes []rune, from int, delim rune) int {
+	for i := from; i+1 < len(runes); i++ {
+		if runes[i] == delim && runes[i+1] == ']' {
+			return i + 1
+		}
+	}
+	return -1
+}
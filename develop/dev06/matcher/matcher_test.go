@@ -0,0 +1,343 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		line     string
+		options  Options
+		expected []Span
+	}{
+		{"o+", "foo boo", Options{Syntax: Extended}, []Span{{1, 3}, {5, 7}}},
+		{"FOO", "foo Foo", Options{Syntax: Fixed, IgnoreCase: true}, []Span{{0, 3}, {4, 7}}},
+		{"foo", "foo foo,foo", Options{Word: true}, []Span{{0, 3}, {4, 7}, {8, 11}}},
+		{"foo", "foofoo foo", Options{Word: true}, []Span{{7, 10}}},
+		{"ж", "ж ж", Options{Word: true}, []Span{{0, 2}, {3, 5}}},
+		{"x*", "abc", Options{}, nil},
+		{"a.c", "abc", Options{Line: true}, []Span{{0, 3}}},
+		{"a.c", "a.c abc", Options{Syntax: Fixed}, []Span{{0, 3}}},
+		{"foo", "foofoo foo", Options{Syntax: Fixed, Word: true}, []Span{{7, 10}}},
+		{"straße", "STRASSE Straße", Options{Syntax: Fixed, IgnoreCase: true}, []Span{{8, 15}}},
+		{"ПРИВЕТ", "привет, Привет", Options{Syntax: Fixed, IgnoreCase: true}, []Span{{0, 12}, {14, 26}}},
+	}
+	for _, test := range tests {
+		m, err := New([]string{test.pattern}, test.options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result := m.Spans(test.line); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Spans(%q, %q) = %v; want %v", test.pattern, test.line, result, test.expected)
+		}
+		// Пустое совпадение ("x*") находит строку, но не дает границ
+		if len(test.expected) > 0 && !m.Match(test.line) {
+			t.Errorf("Match(%q, %q) = false", test.pattern, test.line)
+		}
+	}
+}
+
+func TestFixedMatchersAgree(t *testing.T) {
+	// Прямой поиск фиксированных строк должен совпадать с поиском через RE2
+	patterns := []string{"go", "gopher", "a+b", ""}
+	lines := []string{"", "go gopher", "Go GOPHER", "a+b", "ago", "gopher_go", "Gophers"}
+	for _, options := range []Options{
+		{Syntax: Fixed},
+		{Syntax: Fixed, IgnoreCase: true},
+		{Syntax: Fixed, Word: true},
+		{Syntax: Fixed, IgnoreCase: true, Word: true},
+		{Syntax: Fixed, Line: true},
+	} {
+		for _, pattern := range patterns {
+			literal, _ := New([]string{pattern, "her"}, options)
+			regex, err := NewRegex([]string{pattern, "her"}, options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, line := range lines {
+				if literal.Match(line) != regex.Match(line) {
+					t.Errorf("%+v: Match(%q, %q) differs: literal %v, regex %v",
+						options, pattern, line, literal.Match(line), regex.Match(line))
+				}
+			}
+		}
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	if _, err := New([]string{"a\\(b"}, Options{}); err == nil {
+		t.Errorf("expected error for unbalanced group")
+	}
+}
+
+// recorder запоминает переданные записи и итоги
+type recorder struct {
+	records []Record
+	summary Summary
+}
+
+func (r *recorder) Print(record Record) error {
+	r.records = append(r.records, record)
+	return nil
+}
+
+func (r *recorder) Finish(summary Summary) error {
+	r.summary = summary
+	return nil
+}
+
+func TestSearcherRecords(t *testing.T) {
+	m, err := New([]string{"m"}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	searcher := NewSearcher(m, SearchOptions{Before: 1, After: 1, Spans: true})
+	var r recorder
+	if err := searcher.Search(strings.NewReader("a\nb\nm1\nc\nd\n"), &r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Record{
+		{Kind: BeforeContext, Line: 2, Offset: 2, Text: "b"},
+		{Kind: Match, Line: 3, Offset: 4, Text: "m1", Spans: []Span{{0, 1}}},
+		{Kind: AfterContext, Line: 4, Offset: 7, Text: "c"},
+	}
+	if !reflect.DeepEqual(r.records, expected) {
+		t.Errorf("expected %+v, got %+v", expected, r.records)
+	}
+	if expected := (Summary{Matches: 1, Bytes: 11}); r.summary != expected {
+		t.Errorf("expected %+v, got %+v", expected, r.summary)
+	}
+}
+
+func TestSearcherBinary(t *testing.T) {
+	m, _ := New([]string{"needle"}, Options{})
+	input := "a\x00\nneedle\nneedle\n"
+
+	var r recorder
+	if err := NewSearcher(m, SearchOptions{}).Search(strings.NewReader(input), &r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.records) != 0 || r.summary.Matches != 1 || !r.summary.Binary {
+		t.Errorf("binary: got records %+v, summary %+v", r.records, r.summary)
+	}
+
+	r = recorder{}
+	if err := NewSearcher(m, SearchOptions{BinaryText: true}).Search(strings.NewReader(input), &r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.records) != 2 || r.summary.Binary {
+		t.Errorf("binary as text: got records %+v, summary %+v", r.records, r.summary)
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	for i := 1; i <= 5; i++ {
+		r.push(Record{Line: i})
+	}
+	var nums []int
+	r.drain(func(record Record) { nums = append(nums, record.Line) })
+	if expected := []int{3, 4, 5}; !reflect.DeepEqual(nums, expected) {
+		t.Errorf("expected %v, got %v", expected, nums)
+	}
+	r.drain(func(Record) { t.Errorf("expected empty ring after drain") })
+}
+
+func TestPrinters(t *testing.T) {
+	m, _ := New([]string{"o"}, Options{})
+	lines := []string{"foo", "bar", "baz", "qux", "boo"}
+
+	tests := []struct {
+		name     string
+		options  SearchOptions
+		printer  func(w io.Writer) Printer
+		expected string
+	}{
+		{"plain", SearchOptions{}, func(w io.Writer) Printer {
+			return NewPlainPrinter(w, PlainOptions{Name: "f", WithFilename: true, LineNumbers: true})
+		}, "f:1: foo\nf:5: boo\n"},
+		{"context separator", SearchOptions{After: 1}, func(w io.Writer) Printer {
+			return NewPlainPrinter(w, PlainOptions{Context: true})
+		}, "foo\nbar\n--\nboo\n"},
+		{"only matching with column", SearchOptions{Spans: true}, func(w io.Writer) Printer {
+			return NewPlainPrinter(w, PlainOptions{OnlyMatching: true, Column: true, ByteOffset: true})
+		}, "2:1: o\n3:2: o\n2:17: o\n3:18: o\n"},
+		{"count", SearchOptions{Count: true}, func(w io.Writer) Printer {
+			return NewCountPrinter(w, "f", true)
+		}, "f:2\n"},
+		{"files with matches", SearchOptions{Count: true, MaxCount: 1}, func(w io.Writer) Printer {
+			return NewFilesPrinter(w, "f", false)
+		}, "f\n"},
+		{"files without match", SearchOptions{Count: true, MaxCount: 1}, func(w io.Writer) Printer {
+			return NewFilesPrinter(w, "f", true)
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			if err := NewSearcher(m, tt.options).SearchLines(lines, tt.printer(&output)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestJSONPrinter(t *testing.T) {
+	m, _ := New([]string{"b"}, Options{})
+	stats := NewJSONStats()
+
+	var output bytes.Buffer
+	lines := []string{"a\xffb", "nothing"}
+	if err := NewSearcher(m, SearchOptions{Spans: true}).SearchLines(lines, stats.Printer(&output, "f")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Входы без совпадений не печатаются, но учитываются в итогах
+	if err := NewSearcher(m, SearchOptions{}).SearchLines([]string{"x"}, stats.Printer(&output, "g")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := stats.WriteSummary(&output); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %q", events)
+	}
+	expected := `{"type":"match","data":{"path":{"text":"f"},"lines":{"bytes":"Yf9iCg=="},"line_number":1,` +
+		`"absolute_offset":0,"submatches":[{"match":{"text":"b"},"start":2,"end":3}]}}`
+	if events[1] != expected {
+		t.Errorf("expected %s, got %s", expected, events[1])
+	}
+	if !strings.Contains(events[3], `"searches":2,"searches_with_match":1,"bytes_searched":14,`) {
+		t.Errorf("unexpected summary %s", events[3])
+	}
+
+	// bytes_printed входа и summary одинаково считают события begin и match
+	printed := int64(len(events[0]) + len(events[1]) + 2)
+	for _, event := range events[2:] {
+		var parsed struct {
+			Data struct {
+				Stats struct {
+					BytesPrinted int64 `json:"bytes_printed"`
+				} `json:"stats"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(event), &parsed); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if parsed.Data.Stats.BytesPrinted != printed {
+			t.Errorf("expected bytes_printed %d, got %s", printed, event)
+		}
+	}
+}
+
+// lineGenerator — вход из повторяющегося фрагмента размером limit байт,
+// который не хранит строки в памяти
+type lineGenerator struct {
+	line  []byte
+	pos   int
+	limit int64
+	read  int64
+}
+
+func (g *lineGenerator) Read(p []byte) (int, error) {
+	if g.read >= g.limit {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], g.line[g.pos:])
+		n += copied
+		g.pos = (g.pos + copied) % len(g.line)
+	}
+	if g.read+int64(n) > g.limit {
+		n = int(g.limit - g.read)
+	}
+	g.read += int64(n)
+	return n, nil
+}
+
+func TestSearcherBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("large input")
+	}
+	const size = 64 << 20
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	input := &lineGenerator{line: []byte("some log line without the word\nanother needle here\n"), limit: size}
+	m, err := New([]string{"needle"}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var output bytes.Buffer
+	printer := NewPlainPrinter(&limitedBuffer{&output}, PlainOptions{Context: true})
+	if err := NewSearcher(m, SearchOptions{Before: 3, After: 3}).Search(input, printer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+	if output.Len() == 0 {
+		t.Fatalf("expected matches")
+	}
+	// Вход в 64 МБ не должен оседать в памяти
+	if growth := int64(after.HeapSys) - int64(before.HeapSys); growth > 16<<20 {
+		t.Errorf("heap grew by %d bytes while streaming %d bytes", growth, size)
+	}
+}
+
+// limitedBuffer отбрасывает накопленный вывод, когда он превышает 1 МБ
+type limitedBuffer struct {
+	buf *bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len() > 1<<20 {
+		b.buf.Reset()
+	}
+	return b.buf.Write(p)
+}
+
+func BenchmarkSearcher(b *testing.B) {
+	const size = 16 << 20
+	m, err := New([]string{"ne+dle"}, Options{})
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	benchmarks := []struct {
+		name    string
+		options SearchOptions
+	}{
+		{"plain", SearchOptions{}},
+		{"context", SearchOptions{Before: 5, After: 5}},
+		{"count", SearchOptions{Count: true}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(size)
+			b.ReportAllocs()
+			searcher := NewSearcher(m, bm.options)
+			for i := 0; i < b.N; i++ {
+				input := &lineGenerator{line: []byte("some log line without the word\nanother needle here\nhay\n"), limit: size}
+				var output bytes.Buffer
+				printer := NewPlainPrinter(&limitedBuffer{&output}, PlainOptions{})
+				if err := searcher.Search(input, printer); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package matcher
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// colorMatch и colorReset выделяют совпадение, как GNU grep
+	colorMatch = "\x1b[01;31m\x1b[K"
+	colorReset = "\x1b[m\x1b[K"
+)
+
+// PlainOptions задает формат PlainPrinter
+type PlainOptions struct {
+	Name         string // имя входа для префикса и сообщения о двоичном файле
+	WithFilename bool   // -H, печатать "имя:" перед каждой строкой
+	LineNumbers  bool   // -n
+	Column       bool   // --column, столбец первого совпадения в байтах, начиная с 1
+	ByteOffset   bool   // -b, смещение строки (с OnlyMatching — совпадения)
+	OnlyMatching bool   // -o, печатать только совпадения, по одному в строке
+	Color        bool   // --color, выделять совпадения
+	Context      bool   // печатать "--" между несмежными группами строк
+}
+
+// PlainPrinter печатает строки в формате grep. Каждая строка вывода
+// передается в w одним вызовом Write.
+type PlainPrinter struct {
+	w        io.Writer
+	options  PlainOptions
+	lastLine int // номер последней напечатанной строки; 0 — вывода еще не было
+}
+
+// NewPlainPrinter создает PlainPrinter, пишущий в w
+func NewPlainPrinter(w io.Writer, options PlainOptions) *PlainPrinter {
+	return &PlainPrinter{w: w, options: options}
+}
+
+func (p *PlainPrinter) Print(record Record) error {
+	if p.options.OnlyMatching {
+		if record.Kind != Match {
+			return nil
+		}
+		for _, span := range record.Spans {
+			part := record.Text[span.Start:span.End]
+			line := p.prefix(record.Line, span.Start+1, record.Offset+int64(span.Start)) +
+				p.highlight(part, []Span{{0, len(part)}})
+			if err := p.writeLine(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if p.options.Context && p.lastLine > 0 && record.Line > p.lastLine+1 {
+		if err := p.writeLine("--"); err != nil {
+			return err
+		}
+	}
+	p.lastLine = record.Line
+
+	column := 0
+	if len(record.Spans) > 0 {
+		column = record.Spans[0].Start + 1
+	}
+	return p.writeLine(p.prefix(record.Line, column, record.Offset) + p.highlight(record.Text, record.Spans))
+}
+
+// Finish сообщает о совпадении в двоичном входе
+func (p *PlainPrinter) Finish(summary Summary) error {
+	if summary.Binary && !summary.Skipped && summary.Matches > 0 {
+		_, err := fmt.Fprintf(p.w, "Binary file %s matches\n", p.options.Name)
+		return err
+	}
+	return nil
+}
+
+// writeLine пишет строку вывода с именем входа, если оно требуется
+func (p *PlainPrinter) writeLine(line string) error {
+	if p.options.WithFilename {
+		line = p.options.Name + ":" + line
+	}
+	_, err := io.WriteString(p.w, line+"\n")
+	return err
+}
+
+// prefix возвращает номера перед текстом строки: номер строки (-n),
+// столбец совпадения (--column, 0 — нет совпадения) и смещение (-b)
+func (p *PlainPrinter) prefix(line, column int, offset int64) string {
+	var fields []string
+	if p.options.LineNumbers {
+		fields = append(fields, strconv.Itoa(line))
+	}
+	if p.options.Column && column > 0 {
+		fields = append(fields, strconv.Itoa(column))
+	}
+	if p.options.ByteOffset {
+		fields = append(fields, strconv.FormatInt(offset, 10))
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.Join(fields, ":") + ": "
+}
+
+// highlight выделяет совпадения цветом, если задан Color
+func (p *PlainPrinter) highlight(text string, spans []Span) string {
+	if !p.options.Color || len(spans) == 0 {
+		return text
+	}
+	var b strings.Builder
+	prev := 0
+	for _, span := range spans {
+		b.WriteString(text[prev:span.Start])
+		b.WriteString(colorMatch)
+		b.WriteString(text[span.Start:span.End])
+		b.WriteString(colorReset)
+		prev = span.End
+	}
+	b.WriteString(text[prev:])
+	return b.String()
+}
+
+// CountPrinter печатает только число выбранных строк входа (-c)
+type CountPrinter struct {
+	w            io.Writer
+	name         string
+	withFilename bool
+}
+
+// NewCountPrinter создает CountPrinter; с withFilename перед числом печатается "name:"
+func NewCountPrinter(w io.Writer, name string, withFilename bool) *CountPrinter {
+	return &CountPrinter{w: w, name: name, withFilename: withFilename}
+}
+
+func (p *CountPrinter) Print(Record) error {
+	return nil
+}
+
+func (p *CountPrinter) Finish(summary Summary) error {
+	if summary.Skipped {
+		return nil
+	}
+	prefix := ""
+	if p.withFilename {
+		prefix = p.name + ":"
+	}
+	_, err := fmt.Fprintf(p.w, "%s%d\n", prefix, summary.Matches)
+	return err
+}
+
+// FilesPrinter печатает имя входа, если в нем есть совпадения (-l),
+// или, с without, если их нет (-L)
+type FilesPrinter struct {
+	w       io.Writer
+	name    string
+	without bool
+}
+
+// NewFilesPrinter создает FilesPrinter
+func NewFilesPrinter(w io.Writer, name string, without bool) *FilesPrinter {
+	return &FilesPrinter{w: w, name: name, without: without}
+}
+
+func (p *FilesPrinter) Print(Record) error {
+	return nil
+}
+
+func (p *FilesPrinter) Finish(summary Summary) error {
+	if summary.Skipped || (summary.Matches > 0) == p.without {
+		return nil
+	}
+	_, err := fmt.Fprintln(p.w, p.name)
+	return err
+}
@@ -0,0 +1,230 @@
+package matcher
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+const (
+	// binaryPeekSize — сколько байт в начале входа проверяется на двоичность
+	binaryPeekSize = 8000
+	// readBufferSize — размер буфера чтения; длина строки им не ограничена
+	readBufferSize = 64 << 10
+)
+
+// Kind — вид записи результата поиска
+type Kind int
+
+const (
+	// Match — строка выбрана (совпала или, с Invert, не совпала)
+	Match Kind = iota
+	// BeforeContext — строка контекста перед выбранной (-B)
+	BeforeContext
+	// AfterContext — строка контекста после выбранной (-A)
+	AfterContext
+)
+
+// Record — строка результата поиска
+type Record struct {
+	Kind   Kind
+	Line   int    // номер строки, начиная с 1
+	Offset int64  // смещение начала строки во входе в байтах
+	Text   string // строка без завершающего перевода строки
+	Spans  []Span // совпадения в строке; только для Match без Invert и с SearchOptions.Spans
+}
+
+// Summary — итоги поиска в одном входе
+type Summary struct {
+	Matches int   // число выбранных строк
+	Bytes   int64 // число прочитанных байт
+	Binary  bool  // вход двоичный: записи строк не передаются печати
+	Skipped bool  // двоичный вход пропущен (SearchOptions.SkipBinary)
+}
+
+// SearchOptions задает параметры Searcher
+type SearchOptions struct {
+	Invert     bool // -v, выбирать несовпадающие строки
+	Before     int  // -B, строк контекста перед выбранной
+	After      int  // -A, строк контекста после выбранной
+	MaxCount   int  // -m, прекратить чтение после стольких выбранных строк; 0 — без ограничения
+	Count      bool // нужны только итоги: записи строк не передаются печати
+	Spans      bool // вычислять Record.Spans
+	BinaryText bool // -a, считать двоичный вход текстом
+	SkipBinary bool // -I, пропускать двоичный вход
+}
+
+// Printer получает результаты поиска в одном входе
+type Printer interface {
+	// Print получает очередную запись в порядке строк входа
+	Print(record Record) error
+	// Finish получает итоги по окончании поиска
+	Finish(summary Summary) error
+}
+
+// Searcher просматривает вход построчно и передает записи печати. В памяти
+// хранятся только последние Before строк, поэтому объем входа не ограничен.
+// Searcher не хранит состояния между вызовами Search и может использоваться
+// из нескольких горутин.
+type Searcher struct {
+	matcher Matcher
+	options SearchOptions
+}
+
+// NewSearcher создает Searcher
+func NewSearcher(m Matcher, options SearchOptions) *Searcher {
+	return &Searcher{matcher: m, options: options}
+}
+
+// Search ищет совпадения во входе r и передает результат p
+func (s *Searcher) Search(r io.Reader, p Printer) error {
+	reader := bufio.NewReaderSize(r, readBufferSize)
+	peek, err := reader.Peek(binaryPeekSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+	binary := !s.options.BinaryText && bytes.IndexByte(peek, 0) >= 0
+	if binary && s.options.SkipBinary {
+		return p.Finish(Summary{Binary: true, Skipped: true})
+	}
+
+	options := s.options
+	if binary && !options.Count {
+		// О двоичном входе сообщается лишь факт совпадения
+		options.Count, options.MaxCount = true, 1
+	}
+	state := newSearch(s.matcher, options, p)
+	var offset int64
+	for num := 1; ; num++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		more, perr := state.feed(num, offset, strings.TrimSuffix(line, "\n"))
+		offset += int64(len(line))
+		if perr != nil {
+			return perr
+		}
+		if !more || err == io.EOF {
+			break
+		}
+	}
+	return p.Finish(Summary{Matches: state.matched, Bytes: offset, Binary: binary})
+}
+
+// SearchLines ищет совпадения в готовом наборе строк; смещения считаются
+// так, будто строки разделены одним переводом строки
+func (s *Searcher) SearchLines(lines []string, p Printer) error {
+	state := newSearch(s.matcher, s.options, p)
+	var offset int64
+	for i, line := range lines {
+		more, err := state.feed(i+1, offset, line)
+		offset += int64(len(line)) + 1
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+	}
+	return p.Finish(Summary{Matches: state.matched, Bytes: offset})
+}
+
+// search — состояние поиска в одном входе
+type search struct {
+	matcher   Matcher
+	options   SearchOptions
+	printer   Printer
+	before    ring
+	afterLeft int // сколько строк контекста -A осталось передать
+	matched   int // число выбранных строк
+}
+
+func newSearch(m Matcher, options SearchOptions, p Printer) *search {
+	s := &search{matcher: m, options: options, printer: p}
+	if !options.Count {
+		s.before = newRing(options.Before)
+	}
+	return s
+}
+
+// feed обрабатывает очередную строку и сообщает, нужно ли читать дальше:
+// поиск прекращается после MaxCount выбранных строк и их контекста
+func (s *search) feed(num int, offset int64, text string) (bool, error) {
+	record := Record{Line: num, Offset: offset, Text: text}
+	selected := s.matcher.Match(text) != s.options.Invert
+	switch {
+	case selected && !s.limitReached():
+		s.matched++
+		if !s.options.Count {
+			var err error
+			s.before.drain(func(context Record) {
+				if err == nil {
+					err = s.printer.Print(context)
+				}
+			})
+			if err != nil {
+				return false, err
+			}
+			if s.options.Spans && !s.options.Invert {
+				record.Spans = s.matcher.Spans(text)
+			}
+			if err := s.printer.Print(record); err != nil {
+				return false, err
+			}
+			s.afterLeft = s.options.After
+		}
+	case s.afterLeft > 0:
+		record.Kind = AfterContext
+		if err := s.printer.Print(record); err != nil {
+			return false, err
+		}
+		s.afterLeft--
+	default:
+		record.Kind = BeforeContext
+		s.before.push(record)
+	}
+	return !s.limitReached() || s.afterLeft > 0, nil
+}
+
+// limitReached сообщает, выбрано ли уже MaxCount строк
+func (s *search) limitReached() bool {
+	return s.options.MaxCount > 0 && s.matched >= s.options.MaxCount
+}
+
+// ring хранит последние cap строк для контекста -B
+type ring struct {
+	records []Record
+	start   int
+	size    int
+}
+
+func newRing(capacity int) ring {
+	return ring{records: make([]Record, capacity)}
+}
+
+// push добавляет запись, вытесняя самую старую при заполнении
+func (r *ring) push(record Record) {
+	if len(r.records) == 0 {
+		return
+	}
+	if r.size < len(r.records) {
+		r.records[(r.start+r.size)%len(r.records)] = record
+		r.size++
+		return
+	}
+	r.records[r.start] = record
+	r.start = (r.start + 1) % len(r.records)
+}
+
+// drain передает накопленные записи от старых к новым и очищает буфер
+func (r *ring) drain(fn func(Record)) {
+	for i := 0; i < r.size; i++ {
+		fn(r.records[(r.start+i)%len(r.records)])
+	}
+	r.start, r.size = 0, 0
+}
@@ -15,17 +15,39 @@ package main
 -F - "fixed", точное совпадение со строкой, не паттерн
 -n - "line num", печатать номер строки
 
+Дополнительно поддерживаются регулярные выражения: -G (BRE, по умолчанию),
+-E (ERE), -w и -x для совпадения со словом и со всей строкой, -e и -f
+для задания нескольких шаблонов, рекурсивный поиск (-r, -R) с фильтрами
+--include, --exclude, --exclude-dir и учетом .gitignore, вывод имен файлов
+(-l, -L, -H, -h) и пропуск двоичных файлов (-I). Вход читается потоком:
+в памяти хранится лишь контекст -B, поэтому размер файла не ограничен;
+без файлов читается стандартный ввод, -m NUM прекращает чтение после
+NUM совпадений. --color выделяет совпадения, -o печатает только их,
+-b и --column добавляют смещение в байтах и столбец совпадения.
+--json печатает результаты событиями JSON в духе ripgrep --json.
+-z ищет в файлах, сжатых gzip или bzip2, распаковывая их.
+
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
+
+	"gosort/sortlib"
+	"grep/matcher"
 )
 
+// stdinLabel — имя стандартного ввода в выводе
+const stdinLabel = "(standard input)"
+
 // Options задает флаги фильтрации
 type Options struct {
 	after       int  // -A
@@ -35,7 +57,64 @@ type Options struct {
 	ignoreCase  bool // -i
 	invert      bool // -v
 	fixed       bool // -F
+	extended    bool // -E
+	wordRegexp  bool // -w
+	lineRegexp  bool // -x
 	lineNumbers bool // -n
+	maxCount    int  // -m, прекратить чтение файла после NUM совпадений; 0 — без ограничения
+
+	color        bool // --color, выделять совпадения цветом
+	onlyMatching bool // -o, печатать только совпавшие части строк
+	byteOffset   bool // -b, печатать смещение в байтах
+	column       bool // --column, печатать столбец первого совпадения
+	json         bool // --json, печатать события JSON
+
+	recursive         bool     // -r
+	dereference       bool     // -R, проходить и по символическим ссылкам
+	include           []string // --include, искать только в файлах с подходящими именами
+	exclude           []string // --exclude, пропускать файлы с подходящими именами
+	excludeDir        []string // --exclude-dir, пропускать каталоги с подходящими именами
+	noIgnore          bool     // --no-ignore, не учитывать .gitignore
+	filesWithMatches  bool     // -l
+	filesWithoutMatch bool     // -L
+	withFilename      bool     // -H
+	noFilename        bool     // -h
+	binaryText        bool     // -a, считать двоичные файлы текстом
+	skipBinary        bool     // -I, пропускать двоичные файлы
+	searchZip         bool     // -z, распаковывать входы, сжатые gzip или bzip2
+	workers           int      // число файлов, просматриваемых одновременно
+}
+
+// stringList собирает значения повторяющегося флага
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, "\n")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// readPatterns читает шаблоны из файла, по одному в строке; "-" означает
+// стандартный ввод. Пустая строка файла — пустой шаблон, совпадающий с любой строкой.
+func readPatterns(fileName string) ([]string, error) {
+	file := os.Stdin
+	if fileName != "-" {
+		var err error
+		if file, err = os.Open(fileName); err != nil {
+			return nil, err
+		}
+		defer file.Close()
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	return patterns, scanner.Err()
 }
 
 func main() {
@@ -46,18 +125,80 @@ func main() {
 	count := flag.Bool("c", false, "количество строк")
 	ignoreCase := flag.Bool("i", false, "игнорировать регистр")
 	invert := flag.Bool("v", false, "исключать совпадения")
-	fixed := flag.Bool("F", false, "точное совпадение со строкой")
+	fixed := flag.Bool("F", false, "шаблон — фиксированная строка, а не регулярное выражение")
+	extended := flag.Bool("E", false, "шаблон — расширенное регулярное выражение (ERE)")
+	basic := flag.Bool("G", false, "шаблон — базовое регулярное выражение (BRE, по умолчанию)")
+	wordRegexp := flag.Bool("w", false, "совпадение только с целыми словами")
+	lineRegexp := flag.Bool("x", false, "совпадение только со всей строкой")
 	lineNumbers := flag.Bool("n", false, "печатать номер строки")
+	maxCount := flag.Int("m", 0, "прекратить чтение файла после NUM совпадений")
+	colorMode := flag.String("color", "never", "выделять совпадения цветом: auto, always или never")
+	onlyMatching := flag.Bool("o", false, "печатать только совпавшие части строк, по одной в строке")
+	byteOffset := flag.Bool("b", false, "печатать смещение строки (с -o — совпадения) в байтах от начала файла")
+	column := flag.Bool("column", false, "печатать столбец первого совпадения (в байтах, начиная с 1)")
+	jsonOutput := flag.Bool("json", false, "печатать результаты событиями JSON, по одному в строке")
+	var patterns, include, exclude, excludeDir stringList
+	flag.Var(&patterns, "e", "шаблон (можно указать несколько раз)")
+	patternFile := flag.String("f", "", "читать шаблоны из файла, по одному в строке")
+	recursive := flag.Bool("r", false, "рекурсивный поиск в каталогах")
+	dereference := flag.Bool("R", false, "рекурсивный поиск с переходом по символическим ссылкам")
+	flag.Var(&include, "include", "искать только в файлах, имя которых подходит под шаблон")
+	flag.Var(&exclude, "exclude", "пропускать файлы, имя которых подходит под шаблон")
+	flag.Var(&excludeDir, "exclude-dir", "пропускать каталоги, имя которых подходит под шаблон")
+	noIgnore := flag.Bool("no-ignore", false, "не учитывать файлы .gitignore")
+	filesWithMatches := flag.Bool("l", false, "печатать только имена файлов с совпадениями")
+	filesWithoutMatch := flag.Bool("L", false, "печатать только имена файлов без совпадений")
+	withFilename := flag.Bool("H", false, "печатать имя файла перед каждой строкой")
+	noFilename := flag.Bool("h", false, "не печатать имена файлов")
+	binaryText := flag.Bool("a", false, "обрабатывать двоичные файлы как текст")
+	skipBinary := flag.Bool("I", false, "пропускать двоичные файлы")
+	searchZip := flag.Bool("z", false, "искать в файлах, сжатых gzip или bzip2")
+	workers := flag.Int("j", runtime.NumCPU(), "число файлов, просматриваемых одновременно")
 
 	flag.Parse()
 
-	if len(flag.Args()) < 2 {
-		fmt.Println("Usage: grep [OPTIONS] <pattern> <file>")
-		os.Exit(1)
+	if err := checkModes(*fixed, *extended, *basic); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if *jsonOutput && (*count || *filesWithMatches || *filesWithoutMatch) {
+		fmt.Fprintln(os.Stderr, "Error: --json cannot be combined with -c, -l or -L")
+		os.Exit(2)
+	}
+	color, err := useColor(*colorMode, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	args := flag.Args()
+	if *patternFile != "" {
+		filePatterns, err := readPatterns(*patternFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		patterns = append(patterns, filePatterns...)
+	}
+	// Без -e и -f шаблон — первый аргумент
+	if len(patterns) == 0 && *patternFile == "" && len(args) > 0 {
+		patterns, args = stringList{args[0]}, args[1:]
+	}
+	searchDirs := *recursive || *dereference
+	// Без операндов рекурсивный поиск идет по текущему каталогу,
+	// а обычный читает стандартный ввод
+	if len(args) == 0 {
+		args = []string{"-"}
+		if searchDirs {
+			args = []string{"."}
+		}
 	}
 
-	pattern := flag.Arg(0)
-	fileName := flag.Arg(1)
+	if len(patterns) == 0 && *patternFile == "" {
+		fmt.Println("Usage: grep [OPTIONS] <pattern> [<file>...]")
+		fmt.Println("       grep [OPTIONS] -e <pattern>... | -f <file> [<file>...]")
+		os.Exit(1)
+	}
 
 	options := Options{
 		after:       *after,
@@ -67,162 +208,292 @@ func main() {
 		ignoreCase:  *ignoreCase,
 		invert:      *invert,
 		fixed:       *fixed,
+		extended:    *extended,
+		wordRegexp:  *wordRegexp,
+		lineRegexp:  *lineRegexp,
 		lineNumbers: *lineNumbers,
+		maxCount:    *maxCount,
+
+		color:        color,
+		onlyMatching: *onlyMatching,
+		byteOffset:   *byteOffset,
+		column:       *column,
+		json:         *jsonOutput,
+
+		recursive:         searchDirs,
+		dereference:       *dereference,
+		include:           include,
+		exclude:           exclude,
+		excludeDir:        excludeDir,
+		noIgnore:          *noIgnore,
+		filesWithMatches:  *filesWithMatches,
+		filesWithoutMatch: *filesWithoutMatch,
+		withFilename:      *withFilename,
+		noFilename:        *noFilename,
+		binaryText:        *binaryText,
+		skipBinary:        *skipBinary,
+		searchZip:         *searchZip,
+		workers:           *workers,
 	}
 
-	if err := grep(pattern, fileName, options); err != nil {
+	if err := grepFiles(os.Stdout, patterns, args, options); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func grep(pattern, fileName string, options Options) error {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return err
+// useColor решает, выделять ли совпадения цветом: auto — только если
+// вывод идет на терминал, а TERM не "dumb"
+func useColor(mode string, out *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never", "":
+		return false, nil
+	case "auto":
+		info, err := out.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb", nil
 	}
-	defer file.Close()
+	return false, fmt.Errorf("invalid --color argument: %q", mode)
+}
 
-	scanner := bufio.NewScanner(file)
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return err
+// checkModes проверяет, что задан не более чем один из флагов -F, -E и -G
+func checkModes(modes ...bool) error {
+	count := 0
+	for _, mode := range modes {
+		if mode {
+			count++
+		}
 	}
-
-	if options.context > 0 {
-		options.after = options.context
-		options.before = options.context
+	if count > 1 {
+		return errors.New("conflicting matchers specified")
 	}
+	return nil
+}
 
-	matches := make(map[int]bool)
-
-	for i, line := range lines {
-		lineToMatch := line
-		patternToMatch := pattern
+// fileResult — вывод поиска в одном файле
+type fileResult struct {
+	index int
+	lines chan string // строки вывода; закрывается по окончании поиска в файле
+	err   error       // ошибка поиска; читается после закрытия lines
+}
 
-		if options.ignoreCase {
-			lineToMatch = strings.ToLower(line)
-			patternToMatch = strings.ToLower(pattern)
-		}
+// resultBuffer — сколько строк вывода файл может накопить, пока выводятся
+// предыдущие файлы; ограничивает память при параллельном поиске
+const resultBuffer = 1024
 
-		match := false
-		if options.fixed {
-			match = lineToMatch == patternToMatch
-		} else {
-			match = strings.Contains(lineToMatch, patternToMatch)
-		}
+// lineWriter передает каждый вызов Write печати как строку вывода;
+// печать из пакета matcher пишет по одной строке за вызов
+type lineWriter chan<- string
 
-		if options.invert {
-			match = !match
-		}
+func (w lineWriter) Write(p []byte) (int, error) {
+	w <- strings.TrimSuffix(string(p), "\n")
+	return len(p), nil
+}
 
-		if match {
-			matches[i] = true
-		}
+// newSearcher создает сопоставитель шаблонов и Searcher по флагам
+func newSearcher(patterns []string, options Options) (*matcher.Searcher, error) {
+	syntax := matcher.Basic
+	switch {
+	case options.fixed:
+		syntax = matcher.Fixed
+	case options.extended:
+		syntax = matcher.Extended
 	}
-
-	if options.count {
-		fmt.Println(len(matches))
-		return nil
+	m, err := matcher.New(patterns, matcher.Options{
+		Syntax:     syntax,
+		IgnoreCase: options.ignoreCase,
+		Word:       options.wordRegexp,
+		Line:       options.lineRegexp,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Формирование вывода с учетом флагов -A, -B, -C
-	output := make(map[int]bool)
-	for lineNum := range matches {
-		start := max(0, lineNum-options.before)
-		end := min(len(lines)-1, lineNum+options.after)
-		for i := start; i <= end; i++ {
-			output[i] = true
-		}
+	search := matcher.SearchOptions{
+		Invert:     options.invert,
+		Before:     options.before,
+		After:      options.after,
+		MaxCount:   options.maxCount,
+		Count:      options.count || options.filesWithMatches || options.filesWithoutMatch,
+		Spans:      options.onlyMatching || options.color || options.column || options.json,
+		BinaryText: options.binaryText,
+		SkipBinary: options.skipBinary,
 	}
-
-	// Печать результата
-	for i := 0; i < len(lines); i++ {
-		if output[i] {
-			if options.lineNumbers {
-				fmt.Printf("%d: %s\n", i+1, lines[i])
-			} else {
-				fmt.Println(lines[i])
-			}
-		}
+	if options.context > 0 {
+		search.Before, search.After = options.context, options.context
 	}
-
-	return nil
+	if options.onlyMatching && !options.json {
+		// С -o печатаются только совпадения, без контекста
+		search.Before, search.After = 0, 0
+	}
+	if options.filesWithMatches || options.filesWithoutMatch {
+		// Для -l и -L достаточно первого совпадения
+		search.MaxCount = 1
+	}
+	return matcher.NewSearcher(m, search), nil
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// newPrinter выбирает печать результатов входа name по флагам;
+// stats задан только с --json
+func newPrinter(w io.Writer, name string, options Options, stats *matcher.JSONStats) matcher.Printer {
+	switch {
+	case stats != nil:
+		return stats.Printer(w, name)
+	case options.filesWithMatches || options.filesWithoutMatch:
+		return matcher.NewFilesPrinter(w, name, options.filesWithoutMatch)
+	case options.count:
+		return matcher.NewCountPrinter(w, name, options.withFilename)
 	}
-	return b
+	return matcher.NewPlainPrinter(w, matcher.PlainOptions{
+		Name:         name,
+		WithFilename: options.withFilename,
+		LineNumbers:  options.lineNumbers,
+		Column:       options.column,
+		ByteOffset:   options.byteOffset,
+		OnlyMatching: options.onlyMatching,
+		Color:        options.color,
+		Context:      options.before > 0 || options.after > 0 || options.context > 0,
+	})
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+// searchFile ищет совпадения в файле ("-" — стандартный ввод) и пишет результат в w;
+// с -z сжатый вход распаковывается
+func searchFile(name string, searcher *matcher.Searcher, options Options, stats *matcher.JSONStats, w io.Writer) error {
+	var input io.Reader = os.Stdin
+	label := stdinLabel
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input, label = file, name
 	}
-	return b
+	if options.searchZip {
+		var err error
+		if input, err = sortlib.Decompress(input); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+	}
+	return searcher.Search(input, newPrinter(w, label, options, stats))
 }
 
-func grepLines(pattern string, lines []string, options Options) ([]string, error) {
-	if options.context > 0 {
-		options.after = options.context
-		options.before = options.context
+// grepFiles ищет шаблоны в файлах names (с -r — и в каталогах, "-" —
+// стандартный ввод) и пишет результат в w. Файлы просматриваются
+// параллельно options.workers обработчиками, но выводятся в порядке обхода;
+// вывод первого в очереди файла пишется сразу, по мере чтения. Ошибки
+// отдельных файлов не прерывают поиск и возвращаются вместе.
+func grepFiles(w io.Writer, patterns []string, names []string, options Options) error {
+	searcher, err := newSearcher(patterns, options)
+	if err != nil {
+		return err
+	}
+	if !options.noFilename && (options.recursive || len(names) > 1) {
+		options.withFilename = true
+	}
+	var stats *matcher.JSONStats
+	if options.json {
+		stats = matcher.NewJSONStats()
 	}
 
-	matches := make(map[int]bool)
-
-	for i, line := range lines {
-		lineToMatch := line
-		patternToMatch := pattern
-
-		if options.ignoreCase {
-			lineToMatch = strings.ToLower(line)
-			patternToMatch = strings.ToLower(pattern)
+	jobs := make(chan fileJob)
+	results := make(chan *fileResult)
+	go walkFiles(names, options, jobs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(options.workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result := &fileResult{index: job.index, lines: make(chan string, resultBuffer)}
+				results <- result
+				result.err = job.err
+				if job.err == nil {
+					result.err = searchFile(job.name, searcher, options, stats, lineWriter(result.lines))
+				}
+				close(result.lines)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	writer := bufio.NewWriter(w)
+	// Файлы, начатые раньше очереди, ждут, пока не будут выведены предыдущие
+	pending := make(map[int]*fileResult)
+	var errs []error
+	for next := 0; ; next++ {
+		result, ok := pending[next]
+		for !ok {
+			received, open := <-results
+			if !open {
+				if stats != nil {
+					if err := stats.WriteSummary(writer); err != nil {
+						errs = append(errs, err)
+					}
+				}
+				if err := writer.Flush(); err != nil {
+					errs = append(errs, err)
+				}
+				return errors.Join(errs...)
+			}
+			pending[received.index] = received
+			result, ok = pending[next]
 		}
+		delete(pending, next)
 
-		match := false
-		if options.fixed {
-			match = lineToMatch == patternToMatch
-		} else {
-			match = strings.Contains(lineToMatch, patternToMatch)
+		// Строки дочитываются до конца даже после ошибки записи,
+		// чтобы не заблокировать обработчик
+		for line := range result.lines {
+			writer.WriteString(line)
+			writer.WriteByte('\n')
 		}
-
-		if options.invert {
-			match = !match
+		if result.err != nil {
+			errs = append(errs, result.err)
 		}
-
-		if match {
-			matches[i] = true
+		// Вывод первого файла сбрасывается сразу, чтобы поиск
+		// в одном большом файле печатал результаты по мере чтения
+		if err := writer.Flush(); err != nil {
+			errs = append(errs, err)
 		}
 	}
+}
 
-	if options.count {
-		return []string{fmt.Sprintf("%d", len(matches))}, nil
+func max(a, b int) int {
+	if a > b {
+		return a
 	}
+	return b
+}
 
-	output := make(map[int]bool)
-	for lineNum := range matches {
-		start := max(0, lineNum-options.before)
-		end := min(len(lines)-1, lineNum+options.after)
-		for i := start; i <= end; i++ {
-			output[i] = true
-		}
+func grepLines(patterns []string, lines []string, options Options) ([]string, error) {
+	searcher, err := newSearcher(patterns, options)
+	if err != nil {
+		return nil, err
 	}
 
-	var result []string
-	for i := 0; i < len(lines); i++ {
-		if output[i] {
-			if options.lineNumbers {
-				result = append(result, fmt.Sprintf("%d: %s", i+1, lines[i]))
-			} else {
-				result = append(result, lines[i])
-			}
+	var stats *matcher.JSONStats
+	if options.json {
+		stats = matcher.NewJSONStats()
+	}
+	var output strings.Builder
+	if err := searcher.SearchLines(lines, newPrinter(&output, "", options, stats)); err != nil {
+		return nil, err
+	}
+	if stats != nil {
+		if err := stats.WriteSummary(&output); err != nil {
+			return nil, err
 		}
 	}
-
-	return result, nil
+	if output.Len() == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n"), nil
 }
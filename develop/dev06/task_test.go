@@ -1,7 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -25,6 +32,7 @@ func TestGrepLines(t *testing.T) {
 			expected: []string{
 				"Go is a great language.",
 				"I love programming in Go.",
+				"Gophers are amazing.",
 			},
 		},
 		{
@@ -39,6 +47,7 @@ func TestGrepLines(t *testing.T) {
 			expected: []string{
 				"Go is a great language.",
 				"I love programming in Go.",
+				"Gophers are amazing.",
 			},
 		},
 		{
@@ -49,10 +58,8 @@ func TestGrepLines(t *testing.T) {
 				"I love programming in Go.",
 				"Gophers are amazing.",
 			},
-			options: Options{invert: true},
-			expected: []string{
-				"Gophers are amazing.",
-			},
+			options:  Options{invert: true},
+			expected: nil,
 		},
 		{
 			name:    "line numbers",
@@ -66,6 +73,7 @@ func TestGrepLines(t *testing.T) {
 			expected: []string{
 				"1: Go is a great language.",
 				"2: I love programming in Go.",
+				"3: Gophers are amazing.",
 			},
 		},
 		{
@@ -77,7 +85,7 @@ func TestGrepLines(t *testing.T) {
 				"Gophers are amazing.",
 			},
 			options:  Options{count: true},
-			expected: []string{"2"},
+			expected: []string{"3"},
 		},
 		{
 			name:    "context match",
@@ -104,16 +112,54 @@ func TestGrepLines(t *testing.T) {
 				"Go",
 				"I love programming in Go.",
 			},
-			options: Options{fixed: true},
+			options: Options{fixed: true, lineRegexp: true},
 			expected: []string{
 				"Go",
 			},
 		},
+		{
+			name:    "word match",
+			pattern: "Go",
+			lines: []string{
+				"Go is a great language.",
+				"I love programming in Go.",
+				"Gophers are amazing.",
+			},
+			options: Options{wordRegexp: true},
+			expected: []string{
+				"Go is a great language.",
+				"I love programming in Go.",
+			},
+		},
+		{
+			name:    "inverted word match",
+			pattern: "go",
+			lines: []string{
+				"Go is a great language.",
+				"I love programming in Go.",
+				"Gophers are amazing.",
+			},
+			options: Options{wordRegexp: true, ignoreCase: true, invert: true},
+			expected: []string{
+				"Gophers are amazing.",
+			},
+		},
+		{
+			name:    "word match count",
+			pattern: "Go",
+			lines: []string{
+				"Go is a great language.",
+				"I love programming in Go.",
+				"Gophers are amazing.",
+			},
+			options:  Options{wordRegexp: true, count: true},
+			expected: []string{"2"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := grepLines(tt.pattern, tt.lines, tt.options)
+			result, err := grepLines([]string{tt.pattern}, tt.lines, tt.options)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -124,3 +170,552 @@ func TestGrepLines(t *testing.T) {
 		})
 	}
 }
+
+func TestGrepLinesPatterns(t *testing.T) {
+	lines := []string{
+		"Go is a great language.",
+		"Gophers are amazing.",
+		"a+b=c (sum)",
+		"error: disk full",
+		"warning: cpu hot",
+		"path C:\\temp",
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		options  Options
+		expected []string
+	}{
+		{"basic regex", []string{"^Go.*s"}, Options{}, []string{"Go is a great language.", "Gophers are amazing."}},
+		{"basic literal operators", []string{"a+b"}, Options{}, []string{"a+b=c (sum)"}},
+		{"basic escaped operators", []string{"^\\(error\\|warning\\):"}, Options{}, []string{"error: disk full", "warning: cpu hot"}},
+		{"basic interval", []string{"G\\{1\\}o\\+p"}, Options{}, []string{"Gophers are amazing."}},
+		{"extended alternation", []string{"disk|cpu"}, Options{extended: true}, []string{"error: disk full", "warning: cpu hot"}},
+		{"extended literal parens", []string{"\\(sum\\)$"}, Options{extended: true}, []string{"a+b=c (sum)"}},
+		{"fixed substring", []string{"a+b"}, Options{fixed: true}, []string{"a+b=c (sum)"}},
+		{"fixed ignore case", []string{"GOPHER"}, Options{fixed: true, ignoreCase: true}, []string{"Gophers are amazing."}},
+		{"fixed backslash", []string{"C:\\temp"}, Options{fixed: true}, []string{"path C:\\temp"}},
+		{"word", []string{"a"}, Options{wordRegexp: true}, []string{"Go is a great language.", "a+b=c (sum)"}},
+		{"whole line", []string{"error.*"}, Options{lineRegexp: true}, []string{"error: disk full"}},
+		{"several patterns", []string{"disk", "Gophers"}, Options{}, []string{"Gophers are amazing.", "error: disk full"}},
+		{"bracket with backslash", []string{"C:[\\]"}, Options{}, []string{"path C:\\temp"}},
+		{"posix class", []string{"^[[:upper:]][[:lower:]]*s "}, Options{}, []string{"Gophers are amazing."}},
+		{"leading star is literal", []string{"*"}, Options{}, nil},
+		{"empty pattern matches all", []string{""}, Options{count: true}, []string{"6"}},
+		{"no patterns match nothing", nil, Options{count: true}, []string{"0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := grepLines(tt.patterns, lines, tt.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGrepLinesInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"[abc", "a\\", "\\(a\\)\\1"} {
+		if _, err := grepLines([]string{pattern}, []string{"abc"}, Options{}); err == nil {
+			t.Errorf("expected error for pattern %q", pattern)
+		}
+	}
+}
+
+// writeTree создает во временном каталоге файлы с заданным содержимым;
+// ключи — пути через "/"
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return root
+}
+
+func TestGrepFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt":             "needle one\nhay\n",
+		"b.go":              "hay\nneedle two\n",
+		"empty.txt":         "",
+		"sub/c.txt":         "needle three\n",
+		"sub/skip.log":      "needle log\n",
+		"sub/deep/d.txt":    "needle four\n",
+		"vendor/e.txt":      "needle vendor\n",
+		"bin.dat":           "needle\x00binary\n",
+		".gitignore":        "*.log\n/vendor/\n",
+		"sub/.gitignore":    "deep/\n",
+		".git/config":       "needle git\n",
+		"ignored/.keep.txt": "needle kept\n",
+	})
+	rel := func(names ...string) []string {
+		var result []string
+		for _, name := range names {
+			result = append(result, filepath.Join(root, filepath.FromSlash(name)))
+		}
+		return result
+	}
+	prefixed := func(name, line string) string {
+		return filepath.Join(root, filepath.FromSlash(name)) + ":" + line
+	}
+
+	tests := []struct {
+		name     string
+		files    []string
+		options  Options
+		expected []string
+	}{
+		{
+			name:     "single file without prefix",
+			files:    rel("a.txt"),
+			options:  Options{},
+			expected: []string{"needle one"},
+		},
+		{
+			name:     "several files with prefix",
+			files:    rel("a.txt", "b.go"),
+			options:  Options{lineNumbers: true},
+			expected: []string{prefixed("a.txt", "1: needle one"), prefixed("b.go", "2: needle two")},
+		},
+		{
+			name:    "recursive honors gitignore",
+			files:   []string{root},
+			options: Options{recursive: true},
+			expected: []string{
+				prefixed("a.txt", "needle one"),
+				prefixed("b.go", "needle two"),
+				"Binary file " + filepath.Join(root, "bin.dat") + " matches",
+				prefixed("ignored/.keep.txt", "needle kept"),
+				prefixed("sub/c.txt", "needle three"),
+			},
+		},
+		{
+			name:    "recursive without ignore files",
+			files:   []string{root},
+			options: Options{recursive: true, noIgnore: true, skipBinary: true, filesWithMatches: true},
+			expected: rel(".git/config", "a.txt", "b.go", "ignored/.keep.txt", "sub/c.txt", "sub/deep/d.txt",
+				"sub/skip.log", "vendor/e.txt"),
+		},
+		{
+			name:     "include and exclude-dir",
+			files:    []string{root},
+			options:  Options{recursive: true, include: []string{"*.txt"}, excludeDir: []string{"ignored"}, noFilename: true},
+			expected: []string{"needle one", "needle three"},
+		},
+		{
+			name:     "exclude",
+			files:    []string{root},
+			options:  Options{recursive: true, exclude: []string{"*.txt", "*.dat"}, withFilename: true},
+			expected: []string{prefixed("b.go", "needle two")},
+		},
+		{
+			name:     "files without match",
+			files:    rel("a.txt", "empty.txt", "b.go"),
+			options:  Options{filesWithoutMatch: true},
+			expected: rel("empty.txt"),
+		},
+		{
+			name:     "count per file",
+			files:    rel("a.txt", "empty.txt", "bin.dat"),
+			options:  Options{count: true},
+			expected: []string{prefixed("a.txt", "1"), prefixed("empty.txt", "0"), prefixed("bin.dat", "1")},
+		},
+		{
+			name:     "binary as text",
+			files:    rel("bin.dat"),
+			options:  Options{binaryText: true},
+			expected: []string{"needle\x00binary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, workers := range []int{1, 4} {
+				options := tt.options
+				options.workers = workers
+				var output bytes.Buffer
+				if err := grepFiles(&output, []string{"needle"}, tt.files, options); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				result := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+				if !reflect.DeepEqual(result, tt.expected) {
+					t.Errorf("workers %d: expected %q, got %q", workers, tt.expected, result)
+				}
+			}
+		})
+	}
+}
+
+// jsonEvent — событие вывода --json для проверки в тестах
+type jsonEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Path           struct{ Text string } `json:"path"`
+		Lines          struct{ Text string } `json:"lines"`
+		LineNumber     int                   `json:"line_number"`
+		AbsoluteOffset int64                 `json:"absolute_offset"`
+		Submatches     []struct {
+			Match      struct{ Text string } `json:"match"`
+			Start, End int
+		} `json:"submatches"`
+		Stats struct {
+			Searches          int   `json:"searches"`
+			SearchesWithMatch int   `json:"searches_with_match"`
+			BytesSearched     int64 `json:"bytes_searched"`
+			MatchedLines      int   `json:"matched_lines"`
+			Matches           int   `json:"matches"`
+			Elapsed           struct {
+				Human string `json:"human"`
+			} `json:"elapsed"`
+		} `json:"stats"`
+		ElapsedTotal *struct {
+			Secs  int64 `json:"secs"`
+			Nanos int64 `json:"nanos"`
+		} `json:"elapsed_total"`
+	} `json:"data"`
+}
+
+func TestGrepFilesJSON(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt": "hay\nneedle, needle\nstraw\n",
+		"b.txt": "nothing here\n",
+	})
+	files := []string{filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")}
+
+	var output bytes.Buffer
+	if err := grepFiles(&output, []string{"needle"}, files, Options{json: true, after: 1, workers: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []jsonEvent
+	var types []string
+	for _, line := range strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n") {
+		var event jsonEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		events = append(events, event)
+		types = append(types, event.Type)
+	}
+	if expected := []string{"begin", "match", "context", "end", "summary"}; !reflect.DeepEqual(types, expected) {
+		t.Fatalf("expected events %v, got %v", expected, types)
+	}
+
+	match := events[1].Data
+	if match.Path.Text != files[0] || match.Lines.Text != "needle, needle\n" ||
+		match.LineNumber != 2 || match.AbsoluteOffset != 4 || len(match.Submatches) != 2 {
+		t.Errorf("unexpected match event: %+v", match)
+	}
+	if sub := match.Submatches[1]; sub.Match.Text != "needle" || sub.Start != 8 || sub.End != 14 {
+		t.Errorf("unexpected submatch: %+v", sub)
+	}
+	if context := events[2].Data; context.LineNumber != 3 || context.Lines.Text != "straw\n" || len(context.Submatches) != 0 {
+		t.Errorf("unexpected context event: %+v", context)
+	}
+
+	end := events[3].Data.Stats
+	if end.Searches != 1 || end.SearchesWithMatch != 1 || end.BytesSearched != 25 ||
+		end.MatchedLines != 1 || end.Matches != 2 || !strings.HasSuffix(end.Elapsed.Human, "s") {
+		t.Errorf("unexpected end stats: %+v", end)
+	}
+	summary := events[4].Data
+	if summary.Stats.Searches != 2 || summary.Stats.SearchesWithMatch != 1 || summary.Stats.BytesSearched != 38 ||
+		summary.Stats.Matches != 2 || summary.ElapsedTotal == nil {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestGrepFilesCompressed(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	w.Write([]byte("hay\nneedle gzip\n"))
+	w.Close()
+	// "needle bzip2\n", сжатое bzip2: в стандартной библиотеке нет упаковщика bzip2
+	bzipped := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xd5, 0x7b,
+		0xca, 0xe7, 0x00, 0x00, 0x05, 0x59, 0x80, 0x00, 0x10, 0x40, 0x00, 0x10,
+		0x00, 0x16, 0x25, 0x40, 0x10, 0x20, 0x00, 0x22, 0x00, 0x31, 0x08, 0x06,
+		0x9a, 0x68, 0x9c, 0x37, 0x22, 0x0a, 0x21, 0x2f, 0x17, 0x72, 0x45, 0x38,
+		0x50, 0x90, 0xd5, 0x7b, 0xca, 0xe7,
+	}
+	root := writeTree(t, map[string]string{
+		"a.gz":  gzipped.String(),
+		"b.bz2": string(bzipped),
+		"c.txt": "needle plain\n",
+		"d.zst": "\x28\xb5\x2f\xfd\x04\x58\x11\x00\x00\x78\x0a\xae\xfd\xe9\x22",
+	})
+	files := []string{filepath.Join(root, "a.gz"), filepath.Join(root, "b.bz2"), filepath.Join(root, "c.txt")}
+
+	var output bytes.Buffer
+	if err := grepFiles(&output, []string{"needle"}, files, Options{searchZip: true, noFilename: true, workers: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "needle gzip\nneedle bzip2\nneedle plain\n"; output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+
+	// Без -z сжатые файлы ищутся как есть, то есть как двоичные
+	output.Reset()
+	if err := grepFiles(&output, []string{"needle"}, files, Options{noFilename: true, workers: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := output.String(); strings.Contains(result, "needle gzip") || strings.Contains(result, "needle bzip2") ||
+		!strings.HasSuffix(result, "needle plain\n") {
+		t.Errorf("unexpected output %q", result)
+	}
+
+	err := grepFiles(io.Discard, []string{"needle"}, []string{filepath.Join(root, "d.zst")}, Options{searchZip: true, workers: 1})
+	if err == nil || !strings.Contains(err.Error(), "zstd") {
+		t.Errorf("expected zstd error, got %v", err)
+	}
+}
+
+func TestGrepFilesErrors(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "needle\n", "dir/b.txt": "needle\n"})
+	var output bytes.Buffer
+	files := []string{filepath.Join(root, "missing.txt"), filepath.Join(root, "dir"), filepath.Join(root, "a.txt")}
+	err := grepFiles(&output, []string{"needle"}, files, Options{})
+	if err == nil || !strings.Contains(err.Error(), "missing.txt") || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("expected errors for missing file and directory, got %v", err)
+	}
+	if expected := filepath.Join(root, "a.txt") + ":needle\n"; output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+}
+
+func TestGrepFilesSymlinkCycle(t *testing.T) {
+	root := writeTree(t, map[string]string{"dir/a.txt": "needle\n"})
+	if err := os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "dir", "loop")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	for _, dereference := range []bool{false, true} {
+		var output bytes.Buffer
+		options := Options{recursive: true, dereference: dereference, filesWithMatches: true}
+		if err := grepFiles(&output, []string{"needle"}, []string{root}, options); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := filepath.Join(root, "dir", "a.txt") + "\n"; output.String() != expected {
+			t.Errorf("dereference %v: expected %q, got %q", dereference, expected, output.String())
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	rules := ignoreRules{
+		{pattern: "*.log"},
+		{pattern: "build", dirOnly: true},
+		{pattern: "docs/**/*.tmp", anchored: true},
+		{pattern: "keep.log", negate: true},
+		{base: "sub", pattern: "local", anchored: true},
+	}
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"a.log", false, true},
+		{"x/y/b.log", false, true},
+		{"x/keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"docs/a.tmp", false, true},
+		{"docs/x/y/a.tmp", false, true},
+		{"other/docs/a.tmp", false, false},
+		{"sub/local", false, true},
+		{"local", false, false},
+		{"sub/x/local", false, false},
+	}
+	for _, test := range tests {
+		if result := rules.ignored(test.path, test.isDir); result != test.expected {
+			t.Errorf("ignored(%q, %v) = %v; want %v", test.path, test.isDir, result, test.expected)
+		}
+	}
+}
+
+// lineGenerator — бесконечный (или ограниченный limit байтами) вход,
+// который не хранит строки в памяти
+type lineGenerator struct {
+	line  []byte
+	pos   int
+	limit int64
+	read  int64
+}
+
+func (g *lineGenerator) Read(p []byte) (int, error) {
+	if g.limit > 0 && g.read >= g.limit {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], g.line[g.pos:])
+		n += copied
+		g.pos = (g.pos + copied) % len(g.line)
+	}
+	if g.limit > 0 && g.read+int64(n) > g.limit {
+		n = int(g.limit - g.read)
+	}
+	g.read += int64(n)
+	return n, nil
+}
+
+func TestSearchContextAndMaxCount(t *testing.T) {
+	lines := []string{"m1", "a", "b", "c", "m2", "d", "m3", "e", "f", "g", "h", "m4"}
+
+	tests := []struct {
+		name     string
+		options  Options
+		expected []string
+	}{
+		{"after with separators", Options{after: 1}, []string{"m1", "a", "--", "m2", "d", "m3", "e", "--", "m4"}},
+		{"before with separators", Options{before: 1}, []string{"m1", "--", "c", "m2", "d", "m3", "--", "h", "m4"}},
+		{"context joins adjacent hunks", Options{context: 2, lineNumbers: true}, []string{
+			"1: m1", "2: a", "3: b", "4: c", "5: m2", "6: d", "7: m3", "8: e", "9: f", "10: g", "11: h", "12: m4",
+		}},
+		{"max count", Options{maxCount: 2}, []string{"m1", "m2"}},
+		{"max count keeps trailing context", Options{maxCount: 2, after: 2}, []string{"m1", "a", "b", "--", "m2", "d", "m3"}},
+		{"max count with count", Options{maxCount: 3, count: true}, []string{"3"}},
+		{"no separator without context", Options{}, []string{"m1", "m2", "m3", "m4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := grepLines([]string{"^m"}, lines, tt.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+// searchString ищет pattern во входе r, как в стандартном вводе, и возвращает вывод
+func searchString(t *testing.T, r io.Reader, pattern string, options Options) []string {
+	t.Helper()
+	searcher, err := newSearcher([]string{pattern}, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var output strings.Builder
+	if err := searcher.Search(r, newPrinter(&output, stdinLabel, options, nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+}
+
+func TestSearchReader(t *testing.T) {
+	long := strings.Repeat("x", 200<<10) + "needle"
+
+	tests := []struct {
+		name     string
+		input    string
+		options  Options
+		expected []string
+	}{
+		{"long line", "a\n" + long + "\nb", Options{}, []string{long}},
+		{"last line without newline", "a\nneedle", Options{lineNumbers: true}, []string{"2: needle"}},
+		{"crlf kept", "needle\r\n", Options{}, []string{"needle\r"}},
+		{"stdin label", "needle\n", Options{withFilename: true}, []string{stdinLabel + ":needle"}},
+		{"files with matches", "needle\nneedle\n", Options{filesWithMatches: true}, []string{stdinLabel}},
+		{"count", "needle\nhay\nneedle\n", Options{count: true}, []string{"2"}},
+		{"binary", "a\x00\nneedle\n", Options{}, []string{"Binary file " + stdinLabel + " matches"}},
+		{"binary skipped", "a\x00\nneedle\n", Options{skipBinary: true}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := searchString(t, strings.NewReader(tt.input), "needle", tt.options)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSearchReaderStopsEarly(t *testing.T) {
+	// Бесконечный вход: поиск должен завершиться сам после -m совпадений
+	input := &lineGenerator{line: []byte("hay\nneedle\n")}
+	result := searchString(t, input, "needle", Options{maxCount: 3, lineNumbers: true})
+	if expected := []string{"2: needle", "4: needle", "6: needle"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	input = &lineGenerator{line: []byte("hay\nneedle\n")}
+	if result := searchString(t, input, "needle", Options{filesWithMatches: true}); len(result) != 1 {
+		t.Errorf("expected one file name, got %q", result)
+	}
+}
+
+func TestGrepLinesMatchOutput(t *testing.T) {
+	const (
+		colorMatch = "\x1b[01;31m\x1b[K"
+		colorReset = "\x1b[m\x1b[K"
+	)
+	lines := []string{"Go and go", "nothing", "GOGO"}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		options  Options
+		expected []string
+	}{
+		{"only matching", "go", Options{ignoreCase: true, onlyMatching: true}, []string{"Go", "go", "GO", "GO"}},
+		{"only matching with numbers", "g.", Options{onlyMatching: true, lineNumbers: true, byteOffset: true}, []string{"1:7: go"}},
+		{"only matching words", "go", Options{ignoreCase: true, onlyMatching: true, wordRegexp: true}, []string{"Go", "go"}},
+		{"byte offset", "GO", Options{byteOffset: true}, []string{"18: GOGO"}},
+		{"column", "and\\|GO", Options{column: true, lineNumbers: true}, []string{"1:4: Go and go", "3:1: GOGO"}},
+		{"column without match on inverted lines", "go", Options{column: true, invert: true, ignoreCase: true}, []string{"nothing"}},
+		{"color", "o", Options{color: true, ignoreCase: true}, []string{
+			"G" + colorMatch + "o" + colorReset + " and g" + colorMatch + "o" + colorReset,
+			"n" + colorMatch + "o" + colorReset + "thing",
+			"G" + colorMatch + "O" + colorReset + "G" + colorMatch + "O" + colorReset,
+		}},
+		{"color only matching", "and", Options{color: true, onlyMatching: true}, []string{colorMatch + "and" + colorReset}},
+		{"color context line plain", "nothing", Options{color: true, before: 1}, []string{"Go and go", colorMatch + "nothing" + colorReset}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := grepLines([]string{tt.pattern}, lines, tt.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	for mode, expected := range map[string]bool{"always": true, "never": false, "auto": false} {
+		if result, err := useColor(mode, file); err != nil || result != expected {
+			t.Errorf("useColor(%q) = %v, %v; want %v", mode, result, err, expected)
+		}
+	}
+	if _, err := useColor("sometimes", file); err == nil {
+		t.Errorf("expected error for invalid mode")
+	}
+}
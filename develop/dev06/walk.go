@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// fileJob — файл для поиска или ошибка, возникшая при его обходе
+type fileJob struct {
+	index int // порядковый номер, по которому упорядочивается вывод
+	name  string
+	err   error
+}
+
+// walker перечисляет файлы для поиска: операнды-файлы и, с -r или -R,
+// содержимое каталогов в лексикографическом порядке
+type walker struct {
+	options Options
+	jobs    chan<- fileJob
+	index   int
+	visited map[string]bool // реальные пути пройденных каталогов, защита от циклов ссылок
+}
+
+// walkFiles отправляет в jobs файлы для поиска в порядке операндов
+// ("-" — стандартный ввод) и закрывает канал по окончании обхода
+func walkFiles(names []string, options Options, jobs chan<- fileJob) {
+	defer close(jobs)
+	w := &walker{options: options, jobs: jobs, visited: make(map[string]bool)}
+	for _, name := range names {
+		if name == "-" {
+			w.send(name, nil)
+			continue
+		}
+		info, err := os.Stat(name)
+		switch {
+		case err != nil:
+			w.send(name, err)
+		case !info.IsDir():
+			if selected(name, options) {
+				w.send(name, nil)
+			}
+		case !options.recursive:
+			w.send(name, fmt.Errorf("%s: is a directory", name))
+		default:
+			w.walkDir(name, "", nil)
+		}
+	}
+}
+
+// send отправляет очередной файл или ошибку
+func (w *walker) send(name string, err error) {
+	w.jobs <- fileJob{index: w.index, name: name, err: err}
+	w.index++
+}
+
+// walkDir обходит каталог dir; rel — его путь относительно операнда
+// через "/", rules — действующие правила .gitignore
+func (w *walker) walkDir(dir, rel string, rules ignoreRules) {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		if w.visited[real] {
+			return
+		}
+		w.visited[real] = true
+	}
+	if !w.options.noIgnore {
+		rules = loadIgnoreFile(rules, dir, rel)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.send(dir, err)
+		return
+	}
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		childRel := path.Join(rel, entry.Name())
+		mode := entry.Type()
+
+		if mode&os.ModeSymlink != 0 {
+			// Ссылки внутри каталогов -r пропускает, -R проходит
+			if !w.options.dereference {
+				continue
+			}
+			info, err := os.Stat(name)
+			if err != nil {
+				w.send(name, err)
+				continue
+			}
+			mode = info.Mode().Type()
+		}
+
+		isDir := mode.IsDir()
+		if !w.options.noIgnore && (isDir && entry.Name() == ".git" || rules.ignored(childRel, isDir)) {
+			continue
+		}
+		switch {
+		case isDir:
+			if !matchAny(w.options.excludeDir, entry.Name()) {
+				w.walkDir(name, childRel, rules)
+			}
+		case mode.IsRegular():
+			// Устройства, каналы и сокеты при обходе пропускаются
+			if selected(name, w.options) {
+				w.send(name, nil)
+			}
+		}
+	}
+}
+
+// selected сообщает, проходит ли файл фильтры --include и --exclude;
+// шаблоны сопоставляются с именем файла без каталога
+func selected(name string, options Options) bool {
+	base := filepath.Base(name)
+	if len(options.include) > 0 && !matchAny(options.include, base) {
+		return false
+	}
+	return !matchAny(options.exclude, base)
+}
+
+// matchAny сообщает, совпадает ли имя хотя бы с одним шаблоном
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -10,102 +10,546 @@ package cut
 -d - "delimiter" - использовать другой разделитель
 -s - "separated" - только строки с разделителем
 
+Список полей задается в синтаксисе POSIX: номера и диапазоны через запятую
+(1,3, 2-4, -3, 5-); поля выводятся в порядке входа, каждое не более одного
+раза. --complement выбирает все поля, кроме перечисленных, --output-delimiter
+задает разделитель вывода.
+
+-b и -c выбирают байты и символы по тому же списку. Символы — это символы
+UTF-8, а не байты, поэтому -c не портит кириллицу; -n с -b не разрезает
+многобайтовые символы: диапазон сужается до целых символов, как в POSIX.
+
+--csv разбирает записи по RFC 4180 (поля в кавычках могут содержать
+разделитель и переводы строк), --regex-delimiter делит строки по регулярному
+выражению, например '\s+'. В -f вместо номеров можно указать имена колонок
+из первой строки-заголовка: -f name,email.
+
+Входы — файлы-операнды ("-" или их отсутствие — стандартный ввод); длина
+строки не ограничена, строка без завершающего перевода строки выводится
+с ним. -z делит записи нулевым байтом вместо перевода строки.
+
 Программа должна проходить все тесты. Код должен проходить проверки go vet и golint.
 */
 
 import (
 	"bufio"
+	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
+)
+
+// fieldRange — диапазон номеров полей start-end включительно, начиная с 1;
+// end == 0 означает "до конца строки"
+type fieldRange struct {
+	start, end int
+}
+
+// fieldList — упорядоченные непересекающиеся диапазоны полей
+type fieldList []fieldRange
+
+// contains сообщает, входит ли поле с номером n (начиная с 1) в список
+func (l fieldList) contains(n int) bool {
+	for _, r := range l {
+		if n >= r.start && (r.end == 0 || n <= r.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// cutMode задает, что выбирается из строки
+type cutMode int
+
+const (
+	fieldMode cutMode = iota // -f, поля
+	byteMode                 // -b, байты
+	charMode                 // -c, символы UTF-8
 )
 
 // Options задает параметры утилиты
 type Options struct {
-	fields    []int  // Колонки для выбора
-	delimiter string // Разделитель
-	separated bool   // Только строки с разделителем
+	mode            cutMode   // Что выбирать: поля, байты или символы
+	fields          fieldList // Колонки (с -b и -c — байты или символы) для выбора
+	delimiter       string    // Разделитель
+	separated       bool      // Только строки с разделителем
+	complement      bool      // Выбирать колонки, не вошедшие в fields
+	outputDelimiter string    // Разделитель вывода; пустой — как delimiter, с -b и -c — без разделителя
+	noSplit         bool      // -n, не разрезать многобайтовые символы в режиме -b
+
+	fieldNames     []string       // Имена колонок из заголовка, выбираемые вместе с fields
+	csv            bool           // --csv, записи CSV по RFC 4180; delimiter — одиночный символ
+	delimiterRegex *regexp.Regexp // --regex-delimiter, разделитель-регулярное выражение
+	zeroTerminated bool           // -z, записи завершаются нулевым байтом
 }
 
 func main() {
 	// Определение флагов
-	fields := flag.String("f", "", "выбрать поля (колонки), указать через запятую")
+	fields := flag.String("f", "", "выбрать поля (колонки): номера и диапазоны через запятую, например 1,3-5,7-")
+	byteList := flag.String("b", "", "выбрать байты: номера и диапазоны через запятую")
+	charList := flag.String("c", "", "выбрать символы UTF-8: номера и диапазоны через запятую")
+	noSplit := flag.Bool("n", false, "с -b не разрезать многобайтовые символы")
+	csvMode := flag.Bool("csv", false, "разбирать вход как CSV (RFC 4180); разделитель по умолчанию — запятая")
+	regexDelimiter := flag.String("regex-delimiter", "", "разделять поля по регулярному выражению (вывод — через пробел)")
+	zeroTerminated := flag.Bool("z", false, "записи завершаются нулевым байтом, а не переводом строки")
 	delimiter := flag.String("d", "\t", "использовать другой разделитель (по умолчанию TAB)")
 	separated := flag.Bool("s", false, "только строки с разделителем")
+	complement := flag.Bool("complement", false, "выбрать все поля, кроме указанных")
+	outputDelimiter := flag.String("output-delimiter", "", "разделитель вывода (по умолчанию как -d)")
 
 	flag.Parse()
 
-	// Проверка, указаны ли колонки
-	if *fields == "" {
-		fmt.Fprintln(os.Stderr, "Error: -f flag is required")
+	// Проверка, указан ровно один список
+	mode, list, lists := fieldMode, *fields, 0
+	for _, candidate := range []struct {
+		mode cutMode
+		list string
+	}{{fieldMode, *fields}, {byteMode, *byteList}, {charMode, *charList}} {
+		if candidate.list != "" {
+			mode, list = candidate.mode, candidate.list
+			lists++
+		}
+	}
+	switch {
+	case lists == 0:
+		fmt.Fprintln(os.Stderr, "Error: one of -f, -b or -c is required")
+		os.Exit(1)
+	case lists > 1:
+		fmt.Fprintln(os.Stderr, "Error: only one of -f, -b or -c may be specified")
+		os.Exit(1)
+	case mode != fieldMode && (*separated || *csvMode || *regexDelimiter != ""):
+		fmt.Fprintln(os.Stderr, "Error: -s, --csv and --regex-delimiter make sense only with -f")
+		os.Exit(1)
+	case *csvMode && (*regexDelimiter != "" || *zeroTerminated):
+		fmt.Fprintln(os.Stderr, "Error: --csv cannot be combined with --regex-delimiter or -z")
 		os.Exit(1)
 	}
 
-	// Разбор колонок
-	fieldIndexes, err := parseFields(*fields)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing fields: %v\n", err)
-		os.Exit(1)
+	// Разбор колонок: в -f наряду с номерами допускаются имена
+	var names []string
+	if mode == fieldMode {
+		list, names = splitFieldNames(list)
+	}
+	var fieldIndexes fieldList
+	if list != "" || len(names) == 0 {
+		var err error
+		if fieldIndexes, err = parseFields(list); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing fields: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	delimiterSet := false
+	flag.Visit(func(f *flag.Flag) { delimiterSet = delimiterSet || f.Name == "d" })
+	if *csvMode && !delimiterSet {
+		*delimiter = ","
+	}
+	var delimiterRegex *regexp.Regexp
+	if *regexDelimiter != "" {
+		var err error
+		if delimiterRegex, err = regexp.Compile(*regexDelimiter); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing --regex-delimiter: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	options := Options{
-		fields:    fieldIndexes,
-		delimiter: *delimiter,
-		separated: *separated,
+		mode:            mode,
+		fields:          fieldIndexes,
+		delimiter:       *delimiter,
+		separated:       *separated,
+		complement:      *complement,
+		outputDelimiter: *outputDelimiter,
+		noSplit:         *noSplit,
+
+		fieldNames:     names,
+		csv:            *csvMode,
+		delimiterRegex: delimiterRegex,
+		zeroTerminated: *zeroTerminated,
 	}
 
-	// Чтение из STDIN
-	if err := cut(os.Stdin, os.Stdout, options); err != nil {
+	// Без операндов читается стандартный ввод
+	inputs := flag.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	if err := cutFiles(inputs, os.Stdout, options); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func parseFields(fields string) ([]int, error) {
-	parts := strings.Split(fields, ",")
-	var result []int
-	for _, part := range parts {
-		var index int
-		_, err := fmt.Sscanf(part, "%d", &index)
-		if err != nil || index <= 0 {
-			return nil, fmt.Errorf("invalid field: %s", part)
+// cutFiles обрабатывает входы names по очереди ("-" — стандартный ввод).
+// Ошибка входа не прерывает обработку остальных; ошибки возвращаются
+// вместе, с именем входа. Заголовок для имен колонок берется из каждого входа.
+func cutFiles(names []string, output io.Writer, options Options) error {
+	var errs []error
+	for _, name := range names {
+		if err := cutFile(name, output, options); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
 		}
-		result = append(result, index-1) // Преобразуем к 0-индексации
 	}
-	return result, nil
+	return errors.Join(errs...)
+}
+
+// cutFile обрабатывает один вход
+func cutFile(name string, output io.Writer, options Options) error {
+	if name == "-" {
+		return cut(os.Stdin, output, options)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		// Имя файла добавит cutFiles
+		if pathErr, ok := err.(*os.PathError); ok {
+			return pathErr.Err
+		}
+		return err
+	}
+	defer file.Close()
+	return cut(file, output, options)
+}
+
+// parseFields разбирает список полей POSIX: номера и диапазоны N-M, -M
+// и N- через запятую. Пересекающиеся и смежные диапазоны объединяются.
+func parseFields(fields string) (fieldList, error) {
+	var result fieldList
+	for _, part := range strings.Split(fields, ",") {
+		r, err := parseRange(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+
+	return result.normalized(), nil
+}
+
+// normalized упорядочивает диапазоны и объединяет пересекающиеся и смежные
+func (l fieldList) normalized() fieldList {
+	if len(l) == 0 {
+		return l
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].start < l[j].start })
+	merged := l[:1]
+	for _, r := range l[1:] {
+		last := &merged[len(merged)-1]
+		if last.end != 0 && r.start > last.end+1 {
+			merged = append(merged, r)
+			continue
+		}
+		if last.end != 0 && (r.end == 0 || r.end > last.end) {
+			last.end = r.end
+		}
+	}
+	return merged
+}
+
+// splitFieldNames отделяет от списка полей имена колонок: элемент, в котором
+// есть что-то кроме цифр и "-", считается именем. Возвращает список без имен.
+func splitFieldNames(fields string) (string, []string) {
+	var numeric, names []string
+	for _, part := range strings.Split(fields, ",") {
+		if strings.Trim(part, "0123456789-") != "" {
+			names = append(names, part)
+			continue
+		}
+		numeric = append(numeric, part)
+	}
+	return strings.Join(numeric, ","), names
+}
+
+// resolveNames добавляет к списку полей колонки заголовка header с именами names
+func resolveNames(header []string, options Options) (fieldList, error) {
+	fields := append(fieldList(nil), options.fields...)
+	for _, name := range options.fieldNames {
+		index := -1
+		for i, column := range header {
+			if column == name {
+				index = i + 1
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("unknown field name: %q", name)
+		}
+		fields = append(fields, fieldRange{index, index})
+	}
+	return fields.normalized(), nil
+}
+
+// parseRange разбирает один элемент списка полей
+func parseRange(part string) (fieldRange, error) {
+	startText, endText, isRange := strings.Cut(part, "-")
+	if !isRange {
+		n, err := parseFieldNumber(part)
+		return fieldRange{n, n}, err
+	}
+	if startText == "" && endText == "" {
+		return fieldRange{}, fmt.Errorf("invalid range with no endpoint: %s", part)
+	}
+
+	r := fieldRange{start: 1}
+	var err error
+	if startText != "" {
+		if r.start, err = parseFieldNumber(startText); err != nil {
+			return fieldRange{}, err
+		}
+	}
+	if endText != "" {
+		if r.end, err = parseFieldNumber(endText); err != nil {
+			return fieldRange{}, err
+		}
+		if r.end < r.start {
+			return fieldRange{}, fmt.Errorf("invalid decreasing range: %s", part)
+		}
+	}
+	return r, nil
+}
+
+// parseFieldNumber разбирает номер поля; поля нумеруются с 1
+func parseFieldNumber(text string) (int, error) {
+	n, err := strconv.Atoi(text)
+	if err != nil || strings.HasPrefix(text, "+") {
+		return 0, fmt.Errorf("invalid field value: %q", text)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("fields are numbered from 1: %q", text)
+	}
+	return n, nil
 }
 
 func cut(input io.Reader, output io.Writer, options Options) error {
-	scanner := bufio.NewScanner(input)
+	if options.csv {
+		return cutCSV(input, output, options)
+	}
+
+	terminator := byte('\n')
+	if options.zeroTerminated {
+		terminator = 0
+	}
+	reader := bufio.NewReader(input)
 	writer := bufio.NewWriter(output)
-	defer writer.Flush()
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	for first := true; ; first = false {
+		line, err := reader.ReadString(terminator)
+		if err != nil && err != io.EOF {
+			writer.Flush()
+			return err
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		line = strings.TrimSuffix(line, string(terminator))
 
-		// Разделение строки
-		columns := strings.Split(line, options.delimiter)
+		var result string
+		var ok bool
+		switch options.mode {
+		case byteMode:
+			result, ok = cutBytes(line, options), true
+		case charMode:
+			result, ok = cutChars(line, options), true
+		default:
+			if first && len(options.fieldNames) > 0 {
+				fields, err := resolveNames(splitLine(line, options), options)
+				if err != nil {
+					writer.Flush()
+					return err
+				}
+				options.fields = fields
+			}
+			result, ok = cutFields(line, options)
+		}
+		if ok {
+			writer.WriteString(result)
+			writer.WriteByte(terminator)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
 
-		// Фильтрация строк без разделителя
-		if options.separated && len(columns) < 2 {
-			continue
+	return writer.Flush()
+}
+
+// splitLine делит строку на поля по delimiter или delimiterRegex
+func splitLine(line string, options Options) []string {
+	if options.delimiterRegex != nil {
+		return options.delimiterRegex.Split(line, -1)
+	}
+	return strings.Split(line, options.delimiter)
+}
+
+// selectFields выбирает поля записи в порядке входа; nil означает, что
+// запись не нужно печатать
+func selectFields(columns []string, options Options) []string {
+	// Фильтрация строк без разделителя
+	if options.separated && len(columns) < 2 {
+		return nil
+	}
+
+	// Выбор указанных колонок в порядке входа
+	var selected []string
+	for i, column := range columns {
+		if options.fields.contains(i+1) != options.complement {
+			selected = append(selected, column)
 		}
+	}
+	return selected
+}
+
+// cutFields выбирает поля строки; false означает, что строку не нужно печатать
+func cutFields(line string, options Options) (string, bool) {
+	selected := selectFields(splitLine(line, options), options)
+	if len(selected) == 0 {
+		return "", false
+	}
+
+	outputDelimiter := options.outputDelimiter
+	switch {
+	case outputDelimiter != "":
+	case options.delimiterRegex != nil:
+		outputDelimiter = " "
+	default:
+		outputDelimiter = options.delimiter
+	}
+	return strings.Join(selected, outputDelimiter), true
+}
+
+// cutCSV выбирает поля записей CSV. Поля в кавычках могут содержать
+// разделитель и переводы строк; вывод тоже пишется в CSV с кавычками
+// там, где они нужны.
+func cutCSV(input io.Reader, output io.Writer, options Options) error {
+	comma, err := csvDelimiter(options.delimiter, ',')
+	if err != nil {
+		return err
+	}
+	outComma, err := csvDelimiter(options.outputDelimiter, comma)
+	if err != nil {
+		return err
+	}
 
-		// Выбор указанных колонок
-		var selected []string
-		for _, index := range options.fields {
-			if index < len(columns) {
-				selected = append(selected, columns[index])
+	reader := csv.NewReader(input)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	writer := csv.NewWriter(output)
+	writer.Comma = outComma
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if first && len(options.fieldNames) > 0 {
+			if options.fields, err = resolveNames(record, options); err != nil {
+				return err
+			}
+		}
+		if selected := selectFields(record, options); len(selected) > 0 {
+			if err := writer.Write(selected); err != nil {
+				return err
 			}
 		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvDelimiter возвращает разделитель CSV из строки из одного символа;
+// пустая строка означает fallback
+func csvDelimiter(delimiter string, fallback rune) (rune, error) {
+	if delimiter == "" {
+		return fallback, nil
+	}
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, errors.New("CSV delimiter must be a single character other than a quote or newline")
+	}
+	return r, nil
+}
 
-		// Печать результата
-		if len(selected) > 0 {
-			fmt.Fprintln(writer, strings.Join(selected, options.delimiter))
+// cutBytes выбирает байты строки; с noSplit диапазоны сужаются до целых символов
+func cutBytes(line string, options Options) string {
+	list := options.fields
+	if options.noSplit {
+		list = wholeChars(line, list)
+	}
+	bounds := make([]int, len(line)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	return joinSelected(line, bounds, list, options)
+}
+
+// cutChars выбирает символы UTF-8 строки; байты, не образующие символ,
+// считаются отдельными символами
+func cutChars(line string, options Options) string {
+	bounds := make([]int, 0, len(line)+1)
+	for i := range line {
+		bounds = append(bounds, i)
+	}
+	bounds = append(bounds, len(line))
+	return joinSelected(line, bounds, options.fields, options)
+}
+
+// joinSelected выбирает из строки единицы line[bounds[i]:bounds[i+1]] с номерами
+// из list (или, с complement, не из list) и соединяет смежные участки без
+// разделителя, а несмежные — через outputDelimiter
+func joinSelected(line string, bounds []int, list fieldList, options Options) string {
+	var b strings.Builder
+	inRun, wrote := false, false
+	for i := 0; i+1 < len(bounds); i++ {
+		if list.contains(i+1) == options.complement {
+			inRun = false
+			continue
+		}
+		if !inRun && wrote {
+			b.WriteString(options.outputDelimiter)
 		}
+		b.WriteString(line[bounds[i]:bounds[i+1]])
+		inRun, wrote = true, true
 	}
+	return b.String()
+}
 
-	return scanner.Err()
+// wholeChars сужает диапазоны байтов до целых символов строки по правилам
+// POSIX для cut -b -n: начало сдвигается к первому байту своего символа,
+// конец — к последнему байту предыдущего символа, если попадает внутрь символа.
+// Диапазоны, ставшие пустыми, отбрасываются.
+func wholeChars(line string, list fieldList) fieldList {
+	var result fieldList
+	for _, r := range list {
+		low, high := r.start, r.end
+		if high == 0 || high > len(line) {
+			high = len(line)
+		}
+		if low > high {
+			continue
+		}
+		for low > 1 && !utf8.RuneStart(line[low-1]) {
+			low--
+		}
+		if high < len(line) && !utf8.RuneStart(line[high]) {
+			// Байт high — не последний в символе: берем конец предыдущего
+			start := high - 1
+			for start > 0 && !utf8.RuneStart(line[start]) {
+				start--
+			}
+			high = start
+		}
+		if high == 0 || low > high {
+			continue
+		}
+		result = append(result, fieldRange{low, high})
+	}
+	return result
 }
@@ -2,6 +2,10 @@ package cut
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -17,7 +21,7 @@ func TestCut(t *testing.T) {
 			name:  "basic functionality",
 			input: "name\tage\tcountry\nAlice\t30\tUSA\nBob\t25\tUK",
 			options: Options{
-				fields:    []int{0, 2},
+				fields:    fieldList{{1, 1}, {3, 3}},
 				delimiter: "\t",
 				separated: false,
 			},
@@ -27,7 +31,7 @@ func TestCut(t *testing.T) {
 			name:  "custom delimiter",
 			input: "name|age|country\nAlice|30|USA\nBob|25|UK",
 			options: Options{
-				fields:    []int{1},
+				fields:    fieldList{{2, 2}},
 				delimiter: "|",
 				separated: false,
 			},
@@ -37,7 +41,7 @@ func TestCut(t *testing.T) {
 			name:  "separated only",
 			input: "name\nage\t30\tUSA",
 			options: Options{
-				fields:    []int{0},
+				fields:    fieldList{{1, 1}},
 				delimiter: "\t",
 				separated: true,
 			},
@@ -47,12 +51,113 @@ func TestCut(t *testing.T) {
 			name:  "out of range fields",
 			input: "name\tage\tcountry",
 			options: Options{
-				fields:    []int{5},
+				fields:    fieldList{{6, 6}},
 				delimiter: "\t",
 				separated: false,
 			},
 			expected: "",
 		},
+		{
+			name:  "ranges in input order",
+			input: "a\tb\tc\td\te",
+			options: Options{
+				fields:    fieldList{{1, 2}, {4, 0}},
+				delimiter: "\t",
+			},
+			expected: "a\tb\td\te\n",
+		},
+		{
+			name:  "complement",
+			input: "a,b,c,d\ne,f",
+			options: Options{
+				fields:     fieldList{{2, 3}},
+				delimiter:  ",",
+				complement: true,
+			},
+			expected: "a,d\ne\n",
+		},
+		{
+			name:  "output delimiter",
+			input: "a:b:c",
+			options: Options{
+				fields:          fieldList{{1, 0}},
+				delimiter:       ":",
+				outputDelimiter: " | ",
+			},
+			expected: "a | b | c\n",
+		},
+		{
+			name:     "characters",
+			input:    "привет мир\n\nab",
+			options:  Options{mode: charMode, fields: fieldList{{1, 3}, {8, 0}}},
+			expected: "примир\n\nab\n",
+		},
+		{
+			name:     "characters with output delimiter",
+			input:    "привет",
+			options:  Options{mode: charMode, fields: fieldList{{1, 1}, {3, 3}}, outputDelimiter: ":"},
+			expected: "п:и\n",
+		},
+		{
+			name:     "bytes",
+			input:    "привет\nabcdef",
+			options:  Options{mode: byteMode, fields: fieldList{{1, 4}}},
+			expected: "пр\nabcd\n",
+		},
+		{
+			name:     "bytes split characters",
+			input:    "привет",
+			options:  Options{mode: byteMode, fields: fieldList{{1, 3}}},
+			expected: "п\xd1\n",
+		},
+		{
+			name:     "bytes without splitting characters",
+			input:    "привет\nabc",
+			options:  Options{mode: byteMode, fields: fieldList{{2, 5}, {9, 9}}, noSplit: true},
+			expected: "пр\nbc\n",
+		},
+		{
+			name:     "bytes complement",
+			input:    "abcdef",
+			options:  Options{mode: byteMode, fields: fieldList{{2, 3}}, complement: true},
+			expected: "adef\n",
+		},
+		{
+			name:     "csv quoted fields",
+			input:    "id,name,note\n1,\"Smith, John\",\"multi\nline\"\n2,Ann,\"say \"\"hi\"\"\"\n",
+			options:  Options{csv: true, fields: fieldList{{2, 3}}},
+			expected: "name,note\n\"Smith, John\",\"multi\nline\"\nAnn,\"say \"\"hi\"\"\"\n",
+		},
+		{
+			name:     "csv by header names",
+			input:    "id;name;email\n1;Ann;ann@example.com\n",
+			options:  Options{csv: true, delimiter: ";", outputDelimiter: ",", fieldNames: []string{"email", "id"}},
+			expected: "id,email\n1,ann@example.com\n",
+		},
+		{
+			name:     "regex delimiter",
+			input:    "  a   b\tc\nx  y",
+			options:  Options{delimiterRegex: regexp.MustCompile(`\s+`), fields: fieldList{{2, 3}}},
+			expected: "a b\ny\n",
+		},
+		{
+			name:     "header names with delimiter",
+			input:    "name|age|email\nBob|25|bob@example.com",
+			options:  Options{delimiter: "|", fields: fieldList{{2, 2}}, fieldNames: []string{"email"}},
+			expected: "age|email\n25|bob@example.com\n",
+		},
+		{
+			name:     "long line",
+			input:    "a\t" + strings.Repeat("x", 1<<20) + "\tb\n",
+			options:  Options{delimiter: "\t", fields: fieldList{{1, 1}, {3, 3}}},
+			expected: "a\tb\n",
+		},
+		{
+			name:     "zero terminated",
+			input:    "a,b\nc\x00d,e\x00",
+			options:  Options{delimiter: ",", fields: fieldList{{2, 2}}, zeroTerminated: true},
+			expected: "b\nc\x00e\x00",
+		},
 	}
 
 	for _, tt := range tests {
@@ -71,3 +176,84 @@ func TestCut(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		fields   string
+		expected fieldList
+	}{
+		{"1,3", fieldList{{1, 1}, {3, 3}}},
+		{"2-4", fieldList{{2, 4}}},
+		{"-3", fieldList{{1, 3}}},
+		{"5-", fieldList{{5, 0}}},
+		{"3,1,2", fieldList{{1, 3}}},
+		{"1-3,2-5,7", fieldList{{1, 5}, {7, 7}}},
+		{"4-,2,6-8", fieldList{{2, 2}, {4, 0}}},
+		{"1,1,1", fieldList{{1, 1}}},
+	}
+	for _, tt := range tests {
+		result, err := parseFields(tt.fields)
+		if err != nil {
+			t.Errorf("parseFields(%q): unexpected error: %v", tt.fields, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("parseFields(%q) = %v; want %v", tt.fields, result, tt.expected)
+		}
+	}
+
+	for _, fields := range []string{"", "0", "3-2", "-", "a", "1,", "1-2-3", "+1", "-0"} {
+		if _, err := parseFields(fields); err == nil {
+			t.Errorf("parseFields(%q): expected error", fields)
+		}
+	}
+}
+
+func TestCutErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options Options
+	}{
+		{"unknown field name", "a\tb\n", Options{delimiter: "\t", fieldNames: []string{"c"}}},
+		{"unknown csv field name", "a,b\n", Options{csv: true, fieldNames: []string{"c"}}},
+		{"bad csv quoting", "a,\"b\n", Options{csv: true, fields: fieldList{{1, 1}}}},
+		{"long csv delimiter", "a,b\n", Options{csv: true, delimiter: "::", fields: fieldList{{1, 1}}}},
+	}
+	for _, tt := range tests {
+		if err := cut(strings.NewReader(tt.input), &bytes.Buffer{}, tt.options); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestSplitFieldNames(t *testing.T) {
+	list, names := splitFieldNames("1,name,3-4,e-mail,-2")
+	if list != "1,3-4,-2" || !reflect.DeepEqual(names, []string{"name", "e-mail"}) {
+		t.Errorf("unexpected split: %q, %q", list, names)
+	}
+}
+
+func TestCutFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	missing := filepath.Join(dir, "missing.txt")
+	if err := os.WriteFile(first, []byte("name,age\nAnn,30"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(second, []byte("age,name\n25,Bob\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var output bytes.Buffer
+	options := Options{delimiter: ",", fieldNames: []string{"name"}}
+	err := cutFiles([]string{first, missing, second}, &output, options)
+	if err == nil || !strings.HasPrefix(err.Error(), missing+": ") {
+		t.Errorf("expected error naming %s, got %v", missing, err)
+	}
+	// Заголовок с именами колонок разбирается в каждом файле заново
+	if expected := "name\nAnn\nname\nBob\n"; output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+}
@@ -0,0 +1,214 @@
+// Package chans реализует комбинаторы каналов: Or, OrIndex и And для
+// done-каналов, FanIn, Tee, Bridge и OrDone для потоков значений, а также
+// переходы между done-каналами и context.Context (WithDone, FromContext).
+// Каждый комбинатор принимает context.Context: после его отмены все
+// запущенные горутины завершаются, даже если результат больше никто не читает.
+package chans
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// selectChunk — сколько каналов ждет одна горутина. reflect.Select
+// принимает не больше 65536 случаев, а каждый его вызов стоит O(n),
+// поэтому большие наборы каналов делятся на блоки, которые ждут
+// параллельно: глубина ожидания постоянна, а горутин — n/selectChunk.
+const selectChunk = 4096
+
+// Сигналом done-канала считается успешное чтение из него: закрытие или
+// переданное значение. Значения done-каналов комбинаторы отбрасывают.
+
+// Or возвращает канал, который закрывается, как только подаст сигнал
+// любой из каналов channels или будет отменен ctx. Без каналов
+// возвращается уже закрытый канал. Входы ждет одна горутина на каждые
+// selectChunk каналов; все они завершаются вместе с результатом.
+func Or[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	out := make(chan T)
+	if len(channels) == 0 {
+		close(out)
+		return out
+	}
+	waitFirst(ctx, channels, func(int) { close(out) })
+	return out
+}
+
+// And возвращает канал, который закрывается, когда сигнал подаст каждый
+// из каналов channels, или раньше — при отмене ctx. Без каналов
+// возвращается уже закрытый канал. Каналы ждутся по очереди в одной
+// горутине: порядок сигналов не важен, а общее время линейно по числу
+// каналов, тогда как повторный reflect.Select по оставшимся стоил бы O(n²).
+func And[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	out := make(chan T)
+	if len(channels) == 0 {
+		close(out)
+		return out
+	}
+	go func() {
+		defer close(out)
+		for _, ch := range channels {
+			select {
+			case <-ch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// waitFirst ждет первого сигнала любого из каналов или отмены ctx и ровно
+// один раз вызывает done с номером сработавшего канала (-1 при отмене).
+// Когда один блок каналов срабатывает, остальные перестают ждать.
+func waitFirst[T any](ctx context.Context, channels []<-chan T, done func(index int)) {
+	if len(channels) <= selectChunk {
+		go func() {
+			chosen, _, _ := reflect.Select(selectCases(ctx, channels))
+			done(chosen - 1)
+		}()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var once sync.Once
+	left := int64(chunks(len(channels)))
+	forEachChunk(channels, func(start int, chunk []<-chan T) {
+		if chosen, _, _ := reflect.Select(selectCases(ctx, chunk)); chosen > 0 {
+			once.Do(func() {
+				cancel()
+				done(start + chosen - 1)
+			})
+		}
+		// Последний завершившийся блок сообщает об отмене, если сигнала не было
+		if atomic.AddInt64(&left, -1) == 0 {
+			cancel()
+			once.Do(func() { done(-1) })
+		}
+	})
+}
+
+// chunks возвращает число блоков по selectChunk каналов
+func chunks(n int) int {
+	return (n + selectChunk - 1) / selectChunk
+}
+
+// forEachChunk запускает fn в отдельной горутине для каждого блока каналов;
+// start — номер первого канала блока
+func forEachChunk[T any](channels []<-chan T, fn func(start int, chunk []<-chan T)) {
+	for start := 0; start < len(channels); start += selectChunk {
+		go fn(start, channels[start:min(start+selectChunk, len(channels))])
+	}
+}
+
+// selectCases строит случаи reflect.Select: нулевой — ctx.Done(),
+// за ним по одному на каждый канал
+func selectCases[T any](ctx context.Context, channels []<-chan T) []reflect.SelectCase {
+	cases := make([]reflect.SelectCase, 0, len(channels)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, ch := range channels {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	return cases
+}
+
+// OrDone пересылает значения из in, пока in не закрыт и не отменен ctx;
+// после этого закрывает результат
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case value, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- value:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// FanIn сливает значения всех каналов в один; результат закрывается,
+// когда закрыты все входы или отменен ctx. Порядок значений разных входов
+// не определен.
+func FanIn[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, ch := range channels {
+		wg.Add(1)
+		go func(ch <-chan T) {
+			defer wg.Done()
+			for value := range OrDone(ctx, ch) {
+				select {
+				case out <- value:
+				case <-ctx.Done():
+					// OrDone завершится сам, увидев отмену
+					return
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee дублирует значения in в два канала. Очередное значение читается из in
+// только после того, как предыдущее получили оба выхода, поэтому медленный
+// читатель одного выхода задерживает и другой. Оба выхода закрываются, когда
+// закрыт in или отменен ctx.
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for value := range OrDone(ctx, in) {
+			// Локальные копии обнуляются после отправки, чтобы каждый
+			// выход получил значение ровно один раз
+			first, second := out1, out2
+			for i := 0; i < 2; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case first <- value:
+					first = nil
+				case second <- value:
+					second = nil
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Bridge превращает поток каналов в один поток значений: значения каждого
+// канала пересылаются по порядку, пока он не закроется, затем берется
+// следующий. Результат закрывается, когда закрыт streams или отменен ctx.
+func Bridge[T any](ctx context.Context, streams <-chan (<-chan T)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for stream := range OrDone(ctx, streams) {
+			for value := range OrDone(ctx, stream) {
+				select {
+				case out <- value:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
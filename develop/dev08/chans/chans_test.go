@@ -0,0 +1,434 @@
+package chans
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// checkLeaks запоминает число горутин и по окончании теста проверяет,
+// что запущенные тестом горутины завершились
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				t.Errorf("goroutine leak: %d before, %d after\n%s",
+					before, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+// closed возвращает закрытый канал
+func closed[T any]() <-chan T {
+	ch := make(chan T)
+	close(ch)
+	return ch
+}
+
+// isClosed ждет закрытия канала не дольше timeout
+func isClosed[T any](ch <-chan T, timeout time.Duration) bool {
+	select {
+	case _, ok := <-ch:
+		return !ok
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// values отправляет значения в новый канал и закрывает его
+func values[T any](items ...T) <-chan T {
+	ch := make(chan T, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return ch
+}
+
+func TestOr(t *testing.T) {
+	checkLeaks(t)
+	ctx := context.Background()
+
+	never := make(chan int)
+	if !isClosed(Or(ctx, never, closed[int](), never), time.Second) {
+		t.Errorf("expected Or to close when one input is closed")
+	}
+	if !isClosed(Or[int](ctx), time.Second) {
+		t.Errorf("expected Or without inputs to be closed")
+	}
+
+	signal := make(chan int, 1)
+	signal <- 1
+	if !isClosed(Or(ctx, never, signal), time.Second) {
+		t.Errorf("expected Or to close when an input delivers a value")
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	result := Or(cancelCtx, never)
+	if isClosed(result, 10*time.Millisecond) {
+		t.Fatalf("expected Or to wait for inputs")
+	}
+	cancel()
+	if !isClosed(result, time.Second) {
+		t.Errorf("expected Or to close on cancel")
+	}
+}
+
+func TestOrUnreadResultDoesNotLeak(t *testing.T) {
+	checkLeaks(t)
+	first, second := make(chan int), make(chan int)
+	// Результат не читается: после закрытия входа горутина все равно завершается
+	Or(context.Background(), first, second)
+	close(first)
+}
+
+func TestAnd(t *testing.T) {
+	checkLeaks(t)
+	ctx := context.Background()
+
+	first, second := make(chan int), make(chan int)
+	result := And(ctx, first, second)
+	close(first)
+	if isClosed(result, 10*time.Millisecond) {
+		t.Fatalf("expected And to wait for all inputs")
+	}
+	close(second)
+	if !isClosed(result, time.Second) {
+		t.Errorf("expected And to close when all inputs are closed")
+	}
+	if !isClosed(And[int](ctx), time.Second) {
+		t.Errorf("expected And without inputs to be closed")
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	result = And(cancelCtx, make(chan int))
+	cancel()
+	if !isClosed(result, time.Second) {
+		t.Errorf("expected And to close on cancel")
+	}
+}
+
+func TestFanIn(t *testing.T) {
+	checkLeaks(t)
+	var result []int
+	for value := range FanIn(context.Background(), values(1, 2), values(3), values[int]()) {
+		result = append(result, value)
+	}
+	sort.Ints(result)
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestTee(t *testing.T) {
+	checkLeaks(t)
+	first, second := Tee(context.Background(), values("a", "b", "c"))
+	var got1, got2 []string
+	for first != nil || second != nil {
+		select {
+		case value, ok := <-first:
+			if !ok {
+				first = nil
+				continue
+			}
+			got1 = append(got1, value)
+		case value, ok := <-second:
+			if !ok {
+				second = nil
+				continue
+			}
+			got2 = append(got2, value)
+		}
+	}
+	expected := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(got1, expected) || !reflect.DeepEqual(got2, expected) {
+		t.Errorf("expected %v twice, got %v and %v", expected, got1, got2)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	checkLeaks(t)
+	streams := make(chan (<-chan int), 3)
+	streams <- values(1, 2)
+	streams <- values[int]()
+	streams <- values(3)
+	close(streams)
+
+	var result []int
+	for value := range Bridge(context.Background(), streams) {
+		result = append(result, value)
+	}
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestCancelStopsAbandonedConsumers(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Бесконечный источник, из которого результаты комбинаторов
+	// читаются лишь частично
+	endless := make(chan int)
+	go func() {
+		defer close(endless)
+		for i := 0; ; i++ {
+			select {
+			case endless <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	streams := make(chan (<-chan int))
+	go func() {
+		defer close(streams)
+		select {
+		case streams <- endless:
+		case <-ctx.Done():
+		}
+	}()
+
+	<-OrDone(ctx, endless)
+	<-FanIn(ctx, endless, endless)
+	first, _ := Tee(ctx, endless)
+	<-first
+	<-Bridge(ctx, streams)
+	And(ctx, make(chan int), endless)
+	Or(ctx, make(chan int))
+	cancel()
+}
+
+func TestOrIndex(t *testing.T) {
+	checkLeaks(t)
+	ctx := context.Background()
+
+	never := make(chan int)
+	index, ok := <-OrIndex(ctx, never, never, closed[int]())
+	if !ok || index != 2 {
+		t.Errorf("expected index 2, got %d, %v", index, ok)
+	}
+	if _, ok := <-OrIndex[int](ctx); ok {
+		t.Errorf("expected no index without inputs")
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	result := OrIndex(cancelCtx, never)
+	cancel()
+	if _, ok := <-result; ok {
+		t.Errorf("expected no index after cancel")
+	}
+
+	// Результат не читается, но горутина завершается
+	OrIndex(ctx, closed[int]())
+}
+
+func TestWithDone(t *testing.T) {
+	checkLeaks(t)
+	never, fired := make(chan int), make(chan int)
+	ctx, cancel := WithDone(context.Background(), never, fired)
+	defer cancel()
+
+	if isClosed(ctx.Done(), 10*time.Millisecond) {
+		t.Fatalf("expected context to wait for channels")
+	}
+	close(fired)
+	if !isClosed(ctx.Done(), time.Second) {
+		t.Fatalf("expected context to be canceled")
+	}
+	var done *DoneError
+	if !errors.As(context.Cause(ctx), &done) || done.Index != 1 {
+		t.Errorf("expected cause with index 1, got %v", context.Cause(ctx))
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", ctx.Err())
+	}
+}
+
+func TestWithDoneCancel(t *testing.T) {
+	checkLeaks(t)
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithDone(parent, make(chan int))
+	defer cancel()
+	cancelParent()
+	if !isClosed(ctx.Done(), time.Second) {
+		t.Fatalf("expected context to follow its parent")
+	}
+	if cause := context.Cause(ctx); cause != context.Canceled {
+		t.Errorf("expected parent cause, got %v", cause)
+	}
+
+	ctx, cancel = WithDone(context.Background(), make(chan int))
+	cancel()
+	if cause := context.Cause(ctx); cause != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", cause)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	checkLeaks(t)
+	if FromContext[int](context.Background()) != nil {
+		t.Errorf("expected nil channel for a context that cannot be canceled")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := FromContext[struct{}](ctx)
+	if isClosed(done, 10*time.Millisecond) {
+		t.Fatalf("expected channel to stay open")
+	}
+	cancel()
+	if !isClosed(done, time.Second) {
+		t.Errorf("expected channel to close on cancel")
+	}
+
+	// Каналы и контексты сочетаются: Or от контекста и обычного канала
+	ctx, cancel = context.WithCancel(context.Background())
+	result := Or(context.Background(), FromContext[int](ctx), make(chan int))
+	cancel()
+	if !isClosed(result, time.Second) {
+		t.Errorf("expected Or over a context channel to close")
+	}
+}
+
+// neverChannels возвращает n каналов, которые никогда не подают сигнал
+func neverChannels(n int) []<-chan struct{} {
+	channels := make([]<-chan struct{}, n)
+	for i := range channels {
+		channels[i] = make(chan struct{})
+	}
+	return channels
+}
+
+func TestManyChannels(t *testing.T) {
+	checkLeaks(t)
+	ctx := context.Background()
+	const n = 100000
+
+	// Больше 65536 каналов: reflect.Select без деления на блоки упал бы
+	channels := neverChannels(n)
+	channels[70000] = closed[struct{}]()
+	if index := <-OrIndex(ctx, channels...); index != 70000 {
+		t.Errorf("expected index 70000, got %d", index)
+	}
+	if !isClosed(Or(ctx, channels...), time.Second) {
+		t.Errorf("expected Or to close")
+	}
+
+	all := make([]<-chan struct{}, n)
+	for i := range all {
+		all[i] = closed[struct{}]()
+	}
+	if !isClosed(And(ctx, all...), 5*time.Second) {
+		t.Errorf("expected And to close when all inputs are closed")
+	}
+	last := make(chan struct{})
+	all[n-1] = last
+	if isClosed(And(ctx, all...), 50*time.Millisecond) {
+		t.Errorf("expected And to wait for the last input")
+	}
+	close(last)
+}
+
+func TestGoroutineCount(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, n := range []int{1, selectChunk, 100000} {
+		before := runtime.NumGoroutine()
+		Or(ctx, neverChannels(n)...)
+		And(ctx, neverChannels(n)...)
+		// Одна горутина на блок каналов у Or и одна у And
+		if started, limit := runtime.NumGoroutine()-before, chunks(n)+1; started > limit {
+			t.Errorf("n=%d: %d goroutines started, expected at most %d", n, started, limit)
+		}
+	}
+	cancel()
+}
+
+// recursiveOr — прежняя реализация or для сравнения: цепочка из n горутин,
+// каждая ждет свой канал и результат or для остальных
+func recursiveOr(channels ...<-chan struct{}) <-chan struct{} {
+	switch len(channels) {
+	case 0:
+		return nil
+	case 1:
+		return channels[0]
+	}
+	out := make(chan struct{})
+	go func() {
+		defer close(out)
+		select {
+		case <-channels[0]:
+		case <-recursiveOr(channels[1:]...):
+		}
+	}()
+	return out
+}
+
+// goroutinePerChannelOr — реализация с горутиной на каждый канал
+func goroutinePerChannelOr(channels ...<-chan struct{}) <-chan struct{} {
+	out := make(chan struct{})
+	var once sync.Once
+	done := make(chan struct{})
+	for _, ch := range channels {
+		go func(ch <-chan struct{}) {
+			select {
+			case <-ch:
+				once.Do(func() {
+					close(done)
+					close(out)
+				})
+			case <-done:
+			}
+		}(ch)
+	}
+	return out
+}
+
+func BenchmarkOr(b *testing.B) {
+	implementations := []struct {
+		name string
+		or   func(channels ...<-chan struct{}) <-chan struct{}
+	}{
+		{"chunked-select", func(channels ...<-chan struct{}) <-chan struct{} {
+			return Or(context.Background(), channels...)
+		}},
+		{"recursive", recursiveOr},
+		{"goroutine-per-channel", goroutinePerChannelOr},
+	}
+	for _, n := range []int{10, 1000, 100000} {
+		for _, impl := range implementations {
+			b.Run(fmt.Sprintf("%s/%d", impl.name, n), func(b *testing.B) {
+				if impl.name == "recursive" && n > 10000 {
+					// Цепочка из 100k горутин с вложенными select требует сотен мегабайт стеков
+					b.Skip("too slow")
+				}
+				channels := neverChannels(n)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					// Сигнал подает последний канал: худший случай для цепочки
+					fire := make(chan struct{})
+					channels[n-1] = fire
+					result := impl.or(channels...)
+					close(fire)
+					<-result
+				}
+			})
+		}
+	}
+}
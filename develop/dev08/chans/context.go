@@ -0,0 +1,63 @@
+package chans
+
+import (
+	"context"
+	"fmt"
+)
+
+// DoneError — причина отмены контекста WithDone: сигнал done-канала
+type DoneError struct {
+	Index int // номер сработавшего канала в списке
+}
+
+func (e *DoneError) Error() string {
+	return fmt.Sprintf("done channel %d fired", e.Index)
+}
+
+// OrIndex ждет первого сигнала любого из каналов и передает его номер
+// в результат, после чего закрывает его. При отмене ctx (и без каналов)
+// результат закрывается без значения. Результат буферизован, поэтому
+// горутина завершается, даже если его не читают.
+func OrIndex[T any](ctx context.Context, channels ...<-chan T) <-chan int {
+	out := make(chan int, 1)
+	if len(channels) == 0 {
+		close(out)
+		return out
+	}
+	waitFirst(ctx, channels, func(index int) {
+		if index >= 0 {
+			out <- index
+		}
+		close(out)
+	})
+	return out
+}
+
+// WithDone возвращает контекст, производный от parent, который отменяется,
+// когда подаст сигнал любой из каналов channels. Причина отмены
+// (context.Cause) — *DoneError с номером сработавшего канала. Как и для
+// context.WithCancel, CancelFunc нужно вызвать, когда контекст больше
+// не нужен: это освобождает ожидающую горутину.
+func WithDone[T any](parent context.Context, channels ...<-chan T) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	fired := OrIndex(ctx, channels...)
+	go func() {
+		if index, ok := <-fired; ok {
+			cancel(&DoneError{Index: index})
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// FromContext возвращает done-канал, который закрывается при отмене ctx.
+// Горутина не запускается: канал закрывается через context.AfterFunc.
+// Для контекста, который нельзя отменить, возвращается nil — канал,
+// который никогда не подаст сигнал.
+func FromContext[T any](ctx context.Context) <-chan T {
+	if ctx.Done() == nil {
+		return nil
+	}
+	out := make(chan T)
+	context.AfterFunc(ctx, func() { close(out) })
+	return out
+}
@@ -34,31 +34,24 @@ fmt.Printf(“fone after %v”, time.Since(start))
 */
 
 import (
+	"context"
 	"fmt"
 	"time"
+
+	"orchannel/chans"
 )
 
-// or объединяет один или более done-каналов в один.
+// or объединяет один или более done-каналов в один: результат закрывается,
+// как только закроется любой из них. Реализован через chans.Or, который
+// ждет каналы блоками в нескольких горутинах без рекурсии и не оставляет
+// висящих горутин.
 func or(channels ...<-chan interface{}) <-chan interface{} {
-	out := make(chan interface{})
-	go func() {
-		defer close(out)
-
-		// Если нет каналов, просто завершаем
-		if len(channels) == 0 {
-			return
-		}
+	return chans.Or(context.Background(), channels...)
+}
 
-		// Используем select для ожидания первого закрытого канала
-		select {
-		case <-channels[0]:
-			// Если первый канал закрыт, завершаем горутину
-			return
-		case <-or(channels[1:]...): // Рекурсивно вызываем or для остальных каналов
-			return
-		}
-	}()
-	return out
+// orIndex — вариант or, сообщающий номер канала, закрывшегося первым
+func orIndex(channels ...<-chan interface{}) <-chan int {
+	return chans.OrIndex(context.Background(), channels...)
 }
 
 func main() {
@@ -84,4 +77,8 @@ func main() {
 
 	// Выводим время, через которое завершилось выполнение
 	fmt.Printf("Done after %v\n", time.Since(start))
+
+	// Тот же набор с номером сработавшего канала
+	index := <-orIndex(sig(time.Minute), sig(100*time.Millisecond))
+	fmt.Printf("Channel %d closed first\n", index)
 }
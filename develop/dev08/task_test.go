@@ -1 +1,37 @@
 package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOr(t *testing.T) {
+	sig := func(after time.Duration) <-chan interface{} {
+		c := make(chan interface{})
+		go func() {
+			defer close(c)
+			time.Sleep(after)
+		}()
+		return c
+	}
+
+	start := time.Now()
+	<-or(
+		sig(time.Hour),
+		sig(50*time.Millisecond),
+		sig(time.Minute),
+	)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("or closed after %v, expected about 50ms", elapsed)
+	}
+
+	if index := <-orIndex(sig(time.Hour), sig(10*time.Millisecond)); index != 1 {
+		t.Errorf("expected channel 1 to close first, got %d", index)
+	}
+
+	select {
+	case <-or():
+	case <-time.After(time.Second):
+		t.Errorf("expected or without channels to be closed")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinFunc — встроенная команда: получает аргументы вместе с именем
+// и потоки с примененными перенаправлениями, возвращает код возврата
+type builtinFunc func(sh *shell, args []string, std streams) int
+
+// builtins — встроенные команды шелла
+var builtins = map[string]builtinFunc{
+	"cd":   (*shell).cd,
+	"pwd":  (*shell).pwd,
+	"echo": (*shell).echo,
+	"kill": (*shell).kill,
+	"ps":   (*shell).ps,
+	"jobs": (*shell).jobsBuiltin,
+	"fg":   (*shell).fg,
+	"bg":   (*shell).bg,
+}
+
+// cd меняет текущий каталог
+func (sh *shell) cd(args []string, std streams) int {
+	if len(args) < 2 {
+		fmt.Fprintln(std.stderr, "cd: missing argument")
+		return 1
+	}
+	if err := sh.chdir(args[1]); err != nil {
+		fmt.Fprintln(std.stderr, "cd:", err)
+		return 1
+	}
+	return 0
+}
+
+// pwd печатает текущий каталог
+func (sh *shell) pwd(args []string, std streams) int {
+	dir, err := sh.getwd()
+	if err != nil {
+		fmt.Fprintln(std.stderr, "pwd:", err)
+		return 1
+	}
+	fmt.Fprintln(std.stdout, dir)
+	return 0
+}
+
+// echo печатает аргументы через пробел
+func (sh *shell) echo(args []string, std streams) int {
+	fmt.Fprintln(std.stdout, strings.Join(args[1:], " "))
+	return 0
+}
+
+// jobsBuiltin печатает фоновые и остановленные задания
+func (sh *shell) jobsBuiltin(args []string, std streams) int {
+	sh.reportJobs(std.stdout, true)
+	return 0
+}
+
+// jobArg находит задание по необязательному аргументу встроенной команды
+func (sh *shell) jobArg(args []string) (*job, error) {
+	if len(args) < 2 {
+		return sh.findJob("")
+	}
+	j, err := sh.findJob(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", args[1], err)
+	}
+	return j, nil
+}
+
+// fg продолжает задание на переднем плане и ждет его
+func (sh *shell) fg(args []string, std streams) int {
+	j, err := sh.jobArg(args)
+	if err != nil {
+		fmt.Fprintln(std.stderr, "fg:", err)
+		return 1
+	}
+	fmt.Fprintln(std.stdout, j.text)
+
+	j.foreground = true
+	if sh.tty >= 0 && j.pgid != 0 {
+		if err := setTerminalPgrp(sh.tty, j.pgid); err != nil {
+			fmt.Fprintln(std.stderr, "fg:", err)
+		}
+	}
+	if err := j.resume(); err != nil {
+		fmt.Fprintln(std.stderr, "fg:", err)
+	}
+	return sh.waitForeground(j)
+}
+
+// bg продолжает остановленное задание в фоне
+func (sh *shell) bg(args []string, std streams) int {
+	j, err := sh.jobArg(args)
+	if err != nil {
+		fmt.Fprintln(std.stderr, "bg:", err)
+		return 1
+	}
+	if err := j.resume(); err != nil {
+		fmt.Fprintln(std.stderr, "bg:", err)
+		return 1
+	}
+	j.notified = jobRunning
+	fmt.Fprintf(std.stdout, "[%d] %s &\n", j.id, j.text)
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// expandWords выполняет подстановки в словах команды и возвращает аргументы
+func (sh *shell) expandWords(words []word) []string {
+	var args []string
+	for _, w := range words {
+		args = append(args, sh.expandWord(w)...)
+	}
+	return args
+}
+
+// expandWord выполняет подстановки в слове. Части в кавычках и литералы
+// переходят в аргумент как есть, а результат подстановки без кавычек
+// разбивается по пробелам: "$X" — всегда один аргумент, $X — сколько угодно,
+// в том числе ни одного.
+func (sh *shell) expandWord(w word) []string {
+	var fields []string
+	var current strings.Builder
+	started := false // текущий аргумент начат, даже если пока пуст, как ""
+	flush := func() {
+		if started {
+			fields = append(fields, current.String())
+			current.Reset()
+			started = false
+		}
+	}
+
+	for _, part := range w {
+		value := sh.partValue(part)
+		if part.kind == partLiteral || part.quoted {
+			current.WriteString(value)
+			started = true
+			continue
+		}
+
+		if strings.TrimLeft(value, " \t\n") != value {
+			flush()
+		}
+		for i, field := range strings.Fields(value) {
+			if i > 0 {
+				flush()
+			}
+			current.WriteString(field)
+			started = true
+		}
+		if strings.TrimRight(value, " \t\n") != value {
+			flush()
+		}
+	}
+	flush()
+	return fields
+}
+
+// partValue возвращает значение части слова
+func (sh *shell) partValue(part wordPart) string {
+	switch part.kind {
+	case partVariable:
+		return sh.variable(part.text)
+	case partCommand:
+		return sh.substitute(part.script)
+	}
+	return part.text
+}
+
+// variable возвращает значение переменной; неизвестные переменные пусты
+func (sh *shell) variable(name string) string {
+	switch name {
+	case "?":
+		return strconv.Itoa(sh.status)
+	case "$":
+		return strconv.Itoa(os.Getpid())
+	}
+	return os.Getenv(name)
+}
+
+// substitute выполняет команды подстановки $(...) в подоболочке и
+// возвращает их вывод без завершающих переводов строки
+func (sh *shell) substitute(s *script) string {
+	var out bytes.Buffer
+	sub := sh.subshell()
+	sub.stdout = syncWriter(&out)
+	sub.run(s)
+	return strings.TrimRight(out.String(), "\n")
+}
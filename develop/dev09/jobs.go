@@ -0,0 +1,330 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// jobState — состояние задания
+type jobState int
+
+const (
+	jobRunning jobState = iota
+	jobStopped
+	jobDone
+)
+
+func (s jobState) String() string {
+	switch s {
+	case jobStopped:
+		return "Stopped"
+	case jobDone:
+		return "Done"
+	}
+	return "Running"
+}
+
+// job — конвейер, запущенный в собственной группе процессов. Пока задание
+// выполняется на переднем плане интерактивного шелла, терминал принадлежит
+// его группе, поэтому Ctrl+C и Ctrl+Z получают процессы задания, а не шелл.
+type job struct {
+	id         int    // номер в таблице заданий, 0 — задание еще не в таблице
+	text       string // команда для jobs и уведомлений
+	foreground bool
+	pgid       int // 0, пока не запущен ни один внешний процесс
+
+	cmds     []*exec.Cmd
+	stages   map[int]int // номер стадии конвейера по pid процесса
+	builtins sync.WaitGroup
+
+	mu       sync.Mutex
+	changed  *sync.Cond // сигналит при каждом изменении state
+	state    jobState
+	statuses []int // коды возврата стадий
+
+	notified jobState // последнее состояние, о котором шелл сообщил
+}
+
+func newJob(stages int, foreground bool) *job {
+	j := &job{
+		foreground: foreground,
+		stages:     make(map[int]int),
+		statuses:   make([]int, stages),
+	}
+	j.changed = sync.NewCond(&j.mu)
+	return j
+}
+
+// start запускает внешнюю команду стадии index в группе процессов задания.
+// Первая запущенная команда становится лидером группы; если задание
+// переднего плана, она еще до exec забирает терминал интерактивного шелла.
+func (j *job) start(sh *shell, index int, cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: j.pgid}
+	if j.pgid == 0 && j.foreground && sh.tty >= 0 {
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = sh.tty
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if j.pgid == 0 {
+		j.pgid = cmd.Process.Pid
+	}
+	j.cmds = append(j.cmds, cmd)
+	j.stages[cmd.Process.Pid] = index
+	return nil
+}
+
+// goBuiltin выполняет встроенную команду стадии index в горутине шелла
+func (j *job) goBuiltin(index int, run func() int) {
+	j.builtins.Add(1)
+	go func() {
+		defer j.builtins.Done()
+		j.setStatus(index, run())
+	}()
+}
+
+// setStatus записывает код возврата стадии
+func (j *job) setStatus(index, status int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.statuses[index] = status
+}
+
+// monitor следит за процессами задания, пока все они не завершатся:
+// остановки и продолжения меняют состояние, а коды возврата записываются
+// по стадиям. Вызывается после запуска всех стадий — лидер группы еще не
+// снят с ожидания, поэтому группа существует.
+func (j *job) monitor() {
+	go func() {
+		for live := len(j.cmds); live > 0; {
+			var ws syscall.WaitStatus
+			pid, err := syscall.Wait4(-j.pgid, &ws, syscall.WUNTRACED|syscall.WCONTINUED, nil)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				break
+			}
+
+			j.mu.Lock()
+			switch {
+			case ws.Stopped():
+				j.state = jobStopped
+			case ws.Continued():
+				j.state = jobRunning
+			default:
+				j.statuses[j.stages[pid]] = waitStatus(ws)
+				live--
+			}
+			j.changed.Broadcast()
+			j.mu.Unlock()
+		}
+
+		// Процессы уже сняты с ожидания: Wait только дожидается горутин exec,
+		// копирующих ввод-вывод, а его ошибка ожидания здесь не нужна
+		for _, cmd := range j.cmds {
+			cmd.Wait()
+		}
+		j.builtins.Wait()
+
+		j.mu.Lock()
+		j.state = jobDone
+		j.changed.Broadcast()
+		j.mu.Unlock()
+	}()
+}
+
+// wait ждет, пока задание остановится или завершится, и возвращает
+// его состояние и код возврата последней стадии
+func (j *job) wait() (jobState, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for j.state == jobRunning {
+		j.changed.Wait()
+	}
+	return j.state, j.statuses[len(j.statuses)-1]
+}
+
+// current возвращает состояние задания и код возврата последней стадии
+func (j *job) current() (jobState, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state, j.statuses[len(j.statuses)-1]
+}
+
+// resume продолжает остановленное задание сигналом SIGCONT всей группе
+func (j *job) resume() error {
+	j.mu.Lock()
+	if j.state == jobStopped {
+		j.state = jobRunning
+	}
+	j.mu.Unlock()
+	if j.pgid == 0 {
+		return nil
+	}
+	return syscall.Kill(-j.pgid, syscall.SIGCONT)
+}
+
+// describe описывает состояние для jobs и уведомлений: для завершившегося
+// с ошибкой задания — "Exit N"
+func describe(state jobState, status int) string {
+	if state == jobDone && status != 0 {
+		return fmt.Sprintf("Exit %d", status)
+	}
+	return state.String()
+}
+
+// runJob запускает наблюдение за заданием. Фоновое задание попадает
+// в таблицу заданий, а задание переднего плана шелл ждет. Для фонового
+// задания печатается номер и группа процессов, а если в нем только
+// встроенные команды — один номер.
+func (sh *shell) runJob(j *job) int {
+	j.monitor()
+	if !j.foreground {
+		sh.addJob(j)
+		if j.pgid == 0 {
+			fmt.Fprintf(sh.stderr, "[%d]\n", j.id)
+		} else {
+			fmt.Fprintf(sh.stderr, "[%d] %d\n", j.id, j.pgid)
+		}
+		return 0
+	}
+	return sh.waitForeground(j)
+}
+
+// waitForeground ждет задание переднего плана, пока оно не завершится
+// или не остановится, и возвращает терминал шеллу. Остановленное задание
+// остается в таблице, его код возврата — 128+SIGTSTP.
+func (sh *shell) waitForeground(j *job) int {
+	state, status := j.wait()
+	if sh.tty >= 0 {
+		if err := setTerminalPgrp(sh.tty, sh.pgid); err != nil {
+			fmt.Fprintln(sh.stderr, "myshell:", err)
+		}
+	}
+
+	if state == jobStopped {
+		j.foreground = false
+		if j.id == 0 {
+			sh.addJob(j)
+		}
+		j.notified = jobStopped
+		fmt.Fprintf(sh.stderr, "\n[%d]  %s  %s\n", j.id, state, j.text)
+		return 128 + int(syscall.SIGTSTP)
+	}
+	if sh.tty >= 0 && status == 128+int(syscall.SIGINT) {
+		// После ^C приглашение начинается с новой строки
+		fmt.Fprintln(sh.stderr)
+	}
+	sh.removeJob(j)
+	return status
+}
+
+// addJob добавляет задание в таблицу под номером на единицу больше
+// наибольшего занятого
+func (sh *shell) addJob(j *job) {
+	j.id = 1
+	for _, other := range sh.jobs {
+		j.id = max(j.id, other.id+1)
+	}
+	sh.jobs = append(sh.jobs, j)
+}
+
+// removeJob убирает задание из таблицы
+func (sh *shell) removeJob(j *job) {
+	for i, other := range sh.jobs {
+		if other == j {
+			sh.jobs = append(sh.jobs[:i], sh.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// reportJobs печатает задания таблицы и убирает из нее завершившиеся.
+// Если all ложно, печатаются только задания, состояние которых изменилось
+// с прошлого сообщения: так шелл перед приглашением уведомляет о
+// завершении фоновых заданий.
+func (sh *shell) reportJobs(w io.Writer, all bool) {
+	kept := sh.jobs[:0]
+	for _, j := range sh.jobs {
+		state, status := j.current()
+		if all || state != j.notified {
+			fmt.Fprintf(w, "[%d]  %s  %s\n", j.id, describe(state, status), j.text)
+			j.notified = state
+		}
+		if state != jobDone {
+			kept = append(kept, j)
+		}
+	}
+	sh.jobs = kept
+}
+
+// findJob находит задание по спецификации: %N или N — задание с номером N,
+// пустая строка, %% и %+ — последнее добавленное
+func (sh *shell) findJob(spec string) (*job, error) {
+	if spec == "" || spec == "%%" || spec == "%+" {
+		if len(sh.jobs) == 0 {
+			return nil, errors.New("no current job")
+		}
+		return sh.jobs[len(sh.jobs)-1], nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(spec, "%"))
+	if err == nil {
+		for _, j := range sh.jobs {
+			if j.id == id {
+				return j, nil
+			}
+		}
+	}
+	return nil, errors.New("no such job")
+}
+
+// enableJobControl включает управление терминалом, если tty — терминал:
+// задания переднего плана получают терминал, а Ctrl+C, Ctrl+\ и Ctrl+Z,
+// нажатые, пока шелл ждет ввода, не завершают и не останавливают его.
+// Сигналы перехватываются, а не игнорируются: игнорирование унаследовали бы
+// запущенные команды.
+func (sh *shell) enableJobControl(tty *os.File) {
+	fd := int(tty.Fd())
+	if _, err := terminalPgrp(fd); err != nil {
+		return
+	}
+	sh.tty = fd
+	sh.pgid = syscall.Getpgrp()
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTSTP)
+}
+
+// terminalPgrp возвращает группу процессов переднего плана терминала fd;
+// для файла, который не является терминалом, возвращается ошибка
+func terminalPgrp(fd int) (int, error) {
+	var pgid int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgid)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(pgid), nil
+}
+
+// setTerminalPgrp отдает терминал fd группе pgid. Шелл в этот момент может
+// быть в фоне терминала, поэтому на время вызова SIGTTOU игнорируется,
+// иначе он остановил бы шелл.
+func setTerminalPgrp(fd, pgid int) error {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	p := int32(pgid)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&p)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
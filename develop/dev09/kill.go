@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signals — сигналы, которые kill принимает по имени
+var signals = map[string]syscall.Signal{
+	"HUP":    syscall.SIGHUP,
+	"INT":    syscall.SIGINT,
+	"QUIT":   syscall.SIGQUIT,
+	"ILL":    syscall.SIGILL,
+	"TRAP":   syscall.SIGTRAP,
+	"ABRT":   syscall.SIGABRT,
+	"BUS":    syscall.SIGBUS,
+	"FPE":    syscall.SIGFPE,
+	"KILL":   syscall.SIGKILL,
+	"USR1":   syscall.SIGUSR1,
+	"SEGV":   syscall.SIGSEGV,
+	"USR2":   syscall.SIGUSR2,
+	"PIPE":   syscall.SIGPIPE,
+	"ALRM":   syscall.SIGALRM,
+	"TERM":   syscall.SIGTERM,
+	"CHLD":   syscall.SIGCHLD,
+	"CONT":   syscall.SIGCONT,
+	"STOP":   syscall.SIGSTOP,
+	"TSTP":   syscall.SIGTSTP,
+	"TTIN":   syscall.SIGTTIN,
+	"TTOU":   syscall.SIGTTOU,
+	"URG":    syscall.SIGURG,
+	"XCPU":   syscall.SIGXCPU,
+	"XFSZ":   syscall.SIGXFSZ,
+	"VTALRM": syscall.SIGVTALRM,
+	"PROF":   syscall.SIGPROF,
+	"WINCH":  syscall.SIGWINCH,
+	"IO":     syscall.SIGIO,
+	"SYS":    syscall.SIGSYS,
+}
+
+// parseSignal разбирает сигнал по номеру или имени — с префиксом SIG
+// или без, в любом регистре. Номер 0 только проверяет, что процесс существует.
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 64 {
+			return 0, fmt.Errorf("%s: invalid signal specification", s)
+		}
+		return syscall.Signal(n), nil
+	}
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	if sig, ok := signals[name]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("%s: invalid signal specification", s)
+}
+
+// signalName возвращает имя сигнала без префикса SIG или его номер
+func signalName(sig syscall.Signal) string {
+	for name, known := range signals {
+		if known == sig {
+			return name
+		}
+	}
+	return strconv.Itoa(int(sig))
+}
+
+// kill посылает сигнал процессам, группам процессов (-PGID) и заданиям (%N):
+//
+//	kill [-SIGNAL | -s SIGNAL | -n NUM] pid | -pgid | %job ...
+//	kill -l [сигнал | код возврата ...]
+//
+// По умолчанию посылается SIGTERM. Ошибка для одной цели не мешает
+// остальным, но дает код возврата 1.
+func (sh *shell) kill(args []string, std streams) int {
+	args = args[1:]
+	sig := syscall.SIGTERM
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		var err error
+		switch option := args[0]; option {
+		case "-l", "-L":
+			return listSignals(args[1:], std)
+		case "-s", "-n":
+			if len(args) < 2 {
+				fmt.Fprintf(std.stderr, "kill: %s: option requires an argument\n", option)
+				return 1
+			}
+			sig, err = parseSignal(args[1])
+			args = args[2:]
+		case "--":
+			args = args[1:]
+		default:
+			// Отрицательное число после сигнала — группа процессов,
+			// а первым аргументом — всегда сигнал, как в sh
+			sig, err = parseSignal(option[1:])
+			args = args[1:]
+		}
+		if err != nil {
+			fmt.Fprintln(std.stderr, "kill:", err)
+			return 1
+		}
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(std.stderr, "kill: missing argument")
+		return 1
+	}
+
+	status := 0
+	for _, target := range args {
+		if err := sh.signal(target, sig); err != nil {
+			fmt.Fprintf(std.stderr, "kill: %s: %v\n", target, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// signal посылает сигнал цели kill. Остановленное задание после SIGTERM
+// или SIGHUP продолжается, иначе оно не получит сигнал до fg или bg.
+func (sh *shell) signal(target string, sig syscall.Signal) error {
+	if strings.HasPrefix(target, "%") {
+		j, err := sh.findJob(target)
+		if err != nil {
+			return err
+		}
+		if j.pgid == 0 {
+			return errors.New("job has no processes")
+		}
+		if err := syscall.Kill(-j.pgid, sig); err != nil {
+			return err
+		}
+		if state, _ := j.current(); state == jobStopped && (sig == syscall.SIGTERM || sig == syscall.SIGHUP) {
+			return j.resume()
+		}
+		return nil
+	}
+
+	pid, err := strconv.Atoi(target)
+	if err != nil {
+		return errors.New("arguments must be process or job IDs")
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// listSignals печатает имена сигналов. С аргументами печатает имя для
+// номера или кода возврата 128+N и номер для имени.
+func listSignals(args []string, std streams) int {
+	if len(args) == 0 {
+		numbers := make([]int, 0, len(signals))
+		for _, sig := range signals {
+			numbers = append(numbers, int(sig))
+		}
+		sort.Ints(numbers)
+		for _, n := range numbers {
+			fmt.Fprintf(std.stdout, "%2d) SIG%s\n", n, signalName(syscall.Signal(n)))
+		}
+		return 0
+	}
+
+	status := 0
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n > 128 {
+				n -= 128
+			}
+			fmt.Fprintln(std.stdout, signalName(syscall.Signal(n)))
+			continue
+		}
+		sig, err := parseSignal(arg)
+		if err != nil {
+			fmt.Fprintln(std.stderr, "kill:", err)
+			status = 1
+			continue
+		}
+		fmt.Fprintln(std.stdout, int(sig))
+	}
+	return status
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind — вид лексемы командной строки
+type tokenKind int
+
+const (
+	tokenEOF       tokenKind = iota
+	tokenWord                // слово
+	tokenPipe                // |
+	tokenSemicolon           // ;
+	tokenRParen              // ) — конец подстановки $(...)
+	tokenRedirect            // <, >, >>, >&, << или <<- с необязательным номером дескриптора
+	tokenAmpersand           // & — запуск конвейера в фоне
+)
+
+// token — лексема; у слов заполнено word, у перенаправлений — fd
+type token struct {
+	kind tokenKind
+	word word
+	text string // исходный текст оператора для сообщений об ошибках
+	fd   int    // номер дескриптора перед перенаправлением, -1 если не указан
+}
+
+// errIncomplete сообщает, что строка оборвалась внутри кавычек или подстановки
+var errIncomplete = errors.New("unexpected end of input")
+
+// lexer разбивает командную строку на лексемы. Кавычки и экранирование
+// разбираются сразу: слово состоит из частей, у каждой отмечено, была ли
+// она в кавычках, — от этого зависит разбиение результатов подстановок.
+type lexer struct {
+	input  string
+	pos    int
+	nested bool // лексер внутри $(...): ")" завершает подстановку
+
+	// heredocs — here-документы, тела которых начнутся после ближайшего
+	// перевода строки
+	heredocs []heredoc
+}
+
+// heredoc — here-документ, ожидающий чтения тела
+type heredoc struct {
+	redirect  *redirect
+	delimiter string
+	expand    bool // разделитель без кавычек: в теле выполняются подстановки
+	stripTabs bool // <<-: в начале строк тела убираются табуляции
+}
+
+// next возвращает очередную лексему
+func (l *lexer) next() (token, error) {
+	l.skipBlanks()
+	if l.pos >= len(l.input) {
+		if len(l.heredocs) > 0 {
+			return token{}, fmt.Errorf("here-document delimited by %q: %w", l.heredocs[0].delimiter, errIncomplete)
+		}
+		return token{kind: tokenEOF}, nil
+	}
+	switch c := l.input[l.pos]; c {
+	case '|':
+		l.pos++
+		return token{kind: tokenPipe, text: "|"}, nil
+	case ';':
+		l.pos++
+		return token{kind: tokenSemicolon, text: ";"}, nil
+	case '&':
+		l.pos++
+		return token{kind: tokenAmpersand, text: "&"}, nil
+	case '\n':
+		l.pos++
+		if err := l.readHeredocs(); err != nil {
+			return token{}, err
+		}
+		return token{kind: tokenSemicolon, text: "\n"}, nil
+	case '<', '>':
+		return l.redirect(-1), nil
+	case ')':
+		l.pos++
+		if !l.nested {
+			return token{}, fmt.Errorf("syntax error near unexpected token `)'")
+		}
+		return token{kind: tokenRParen, text: ")"}, nil
+	case '(':
+		return token{}, fmt.Errorf("syntax error near unexpected token `('")
+	}
+
+	// Число сразу перед < или > — номер перенаправляемого дескриптора
+	end := l.pos
+	for end < len(l.input) && l.input[end] >= '0' && l.input[end] <= '9' {
+		end++
+	}
+	if end > l.pos && end < len(l.input) && (l.input[end] == '<' || l.input[end] == '>') {
+		fd, err := strconv.Atoi(l.input[l.pos:end])
+		if err != nil {
+			return token{}, fmt.Errorf("%s: bad file descriptor", l.input[l.pos:end])
+		}
+		l.pos = end
+		return l.redirect(fd), nil
+	}
+
+	w, err := l.word()
+	if err != nil {
+		return token{}, err
+	}
+	return token{kind: tokenWord, word: w}, nil
+}
+
+// redirect читает оператор перенаправления
+func (l *lexer) redirect(fd int) token {
+	op := l.input[l.pos : l.pos+1]
+	for _, long := range []string{"<<-", "<<", ">>", ">&"} {
+		if strings.HasPrefix(l.input[l.pos:], long) {
+			op = long
+			break
+		}
+	}
+	l.pos += len(op)
+	return token{kind: tokenRedirect, text: op, fd: fd}
+}
+
+// readHeredocs читает тела ожидающих here-документов: строки до строки,
+// совпадающей с разделителем
+func (l *lexer) readHeredocs() error {
+	for _, h := range l.heredocs {
+		var body strings.Builder
+		for {
+			if l.pos >= len(l.input) {
+				return fmt.Errorf("here-document delimited by %q: %w", h.delimiter, errIncomplete)
+			}
+			line := l.input[l.pos:]
+			end := strings.IndexByte(line, '\n')
+			if end >= 0 {
+				line = line[:end]
+				l.pos += end + 1
+			} else {
+				l.pos = len(l.input)
+			}
+			if h.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == h.delimiter {
+				break
+			}
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+
+		h.redirect.target = word{{kind: partLiteral, text: body.String(), quoted: true}}
+		if h.expand {
+			// В теле действуют подстановки, а "\" экранирует только $ ` \ и перевод строки
+			sub := &lexer{input: body.String()}
+			w, err := sub.expandable(0, "$`\\\n")
+			if err != nil {
+				return err
+			}
+			h.redirect.target = w
+		}
+	}
+	l.heredocs = nil
+	return nil
+}
+
+// skipBlanks пропускает пробелы, табуляции, продолжения строк "\<перевод строки>"
+// и комментарии от "#" в начале слова до конца строки
+func (l *lexer) skipBlanks() {
+	for l.pos < len(l.input) {
+		switch {
+		case l.input[l.pos] == ' ' || l.input[l.pos] == '\t':
+			l.pos++
+		case strings.HasPrefix(l.input[l.pos:], "\\\n"):
+			l.pos += 2
+		case l.input[l.pos] == '#':
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// isWordEnd сообщает, завершает ли символ слово вне кавычек
+func isWordEnd(c byte) bool {
+	return strings.IndexByte(" \t\n|;&()<>", c) >= 0
+}
+
+// word читает слово до пробела или оператора
+func (l *lexer) word() (word, error) {
+	var w word
+	var literal strings.Builder
+	// flush переносит накопленный литерал без кавычек в слово
+	flush := func() {
+		if literal.Len() > 0 {
+			w = append(w, wordPart{kind: partLiteral, text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for l.pos < len(l.input) && !isWordEnd(l.input[l.pos]) {
+		switch c := l.input[l.pos]; c {
+		case '\\':
+			l.pos++
+			switch {
+			case l.pos >= len(l.input):
+				// Одиночная обратная косая черта в конце строки
+				literal.WriteByte('\\')
+			case l.input[l.pos] == '\n':
+				l.pos++
+			default:
+				literal.WriteByte(l.input[l.pos])
+				l.pos++
+			}
+		case '\'':
+			end := strings.IndexByte(l.input[l.pos+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote: %w", errIncomplete)
+			}
+			flush()
+			w = append(w, wordPart{kind: partLiteral, text: l.input[l.pos+1 : l.pos+1+end], quoted: true})
+			l.pos += end + 2
+		case '"':
+			flush()
+			parts, err := l.doubleQuoted()
+			if err != nil {
+				return nil, err
+			}
+			w = append(w, parts...)
+		case '$':
+			part, ok, err := l.dollar(false)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				literal.WriteByte('$')
+				continue
+			}
+			flush()
+			w = append(w, part)
+		default:
+			literal.WriteByte(c)
+			l.pos++
+		}
+	}
+	flush()
+	return w, nil
+}
+
+// doubleQuoted читает строку в двойных кавычках, начиная с открывающей.
+// Внутри действуют подстановки, а "\" экранирует только $ ` " \ и перевод строки.
+func (l *lexer) doubleQuoted() (word, error) {
+	l.pos++
+	w, err := l.expandable('"', "$`\"\\\n")
+	if err != nil {
+		return nil, fmt.Errorf("unterminated double quote: %w", err)
+	}
+	return w, nil
+}
+
+// expandable читает текст с подстановками, но без разбиения на слова,
+// до символа closing или, если он нулевой, до конца ввода. "\" экранирует
+// только символы escapable. Все части результата отмечены как взятые в кавычки.
+func (l *lexer) expandable(closing byte, escapable string) (word, error) {
+	// Пустые кавычки дают пустую часть, чтобы "" оставалось отдельным аргументом
+	w := word{{kind: partLiteral, quoted: true}}
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			w = append(w, wordPart{kind: partLiteral, text: literal.String(), quoted: true})
+			literal.Reset()
+		}
+	}
+
+	// done завершает текст
+	done := func() word {
+		flush()
+		if len(w) > 1 {
+			w = w[1:]
+		}
+		return w
+	}
+
+	for l.pos < len(l.input) {
+		switch c := l.input[l.pos]; c {
+		case closing:
+			l.pos++
+			return done(), nil
+		case '\\':
+			if l.pos+1 < len(l.input) && strings.IndexByte(escapable, l.input[l.pos+1]) >= 0 {
+				if l.input[l.pos+1] != '\n' {
+					literal.WriteByte(l.input[l.pos+1])
+				}
+				l.pos += 2
+				continue
+			}
+			literal.WriteByte(c)
+			l.pos++
+		case '$':
+			part, ok, err := l.dollar(true)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				literal.WriteByte('$')
+				continue
+			}
+			flush()
+			w = append(w, part)
+		default:
+			literal.WriteByte(c)
+			l.pos++
+		}
+	}
+	if closing == 0 {
+		return done(), nil
+	}
+	return nil, errIncomplete
+}
+
+// dollar разбирает подстановку, начинающуюся с "$": $NAME, ${NAME}, $?, $$
+// и $(...). Если за "$" нет подстановки, возвращает false и пропускает "$".
+func (l *lexer) dollar(quoted bool) (wordPart, bool, error) {
+	start := l.pos
+	l.pos++
+	if l.pos >= len(l.input) {
+		return wordPart{}, false, nil
+	}
+
+	switch c := l.input[l.pos]; {
+	case c == '?' || c == '$':
+		l.pos++
+		return wordPart{kind: partVariable, text: string(c), quoted: quoted}, true, nil
+	case c == '{':
+		end := strings.IndexByte(l.input[l.pos:], '}')
+		if end < 0 {
+			return wordPart{}, false, fmt.Errorf("unterminated ${: %w", errIncomplete)
+		}
+		name := l.input[l.pos+1 : l.pos+end]
+		if !isName(name) && name != "?" && name != "$" {
+			return wordPart{}, false, fmt.Errorf("%s: bad substitution", l.input[start:l.pos+end+1])
+		}
+		l.pos += end + 1
+		return wordPart{kind: partVariable, text: name, quoted: quoted}, true, nil
+	case c == '(':
+		l.pos++
+		p := newParser(&lexer{input: l.input, pos: l.pos, nested: true})
+		script, err := p.parseScript()
+		if err != nil {
+			return wordPart{}, false, err
+		}
+		l.pos = p.lex.pos
+		return wordPart{kind: partCommand, script: script, quoted: quoted}, true, nil
+	case isNameStart(c):
+		end := l.pos
+		for end < len(l.input) && isNameChar(l.input[end]) {
+			end++
+		}
+		name := l.input[l.pos:end]
+		l.pos = end
+		return wordPart{kind: partVariable, text: name, quoted: quoted}, true, nil
+	}
+	return wordPart{}, false, nil
+}
+
+// isName сообщает, является ли строка именем переменной
+func isName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
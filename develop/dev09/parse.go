@@ -0,0 +1,253 @@
+package main
+
+import "fmt"
+
+// partKind — вид части слова
+type partKind int
+
+const (
+	partLiteral  partKind = iota // текст без подстановок
+	partVariable                 // $NAME, ${NAME}, $? или $$
+	partCommand                  // $(...)
+)
+
+// wordPart — часть слова. quoted отмечает части в кавычках: результаты
+// подстановок вне кавычек разбиваются на отдельные аргументы по пробелам.
+type wordPart struct {
+	kind   partKind
+	text   string  // текст литерала или имя переменной
+	script *script // команды подстановки $(...)
+	quoted bool
+}
+
+// word — слово командной строки; после подстановок дает ноль или больше аргументов
+type word []wordPart
+
+// redirectKind — вид перенаправления
+type redirectKind int
+
+const (
+	redirectIn      redirectKind = iota // <файл
+	redirectOut                         // >файл
+	redirectAppend                      // >>файл
+	redirectDup                         // n>&m: дескриптор n — копия m
+	redirectHeredoc                     // <<РАЗДЕЛИТЕЛЬ: ввод из тела here-документа
+)
+
+// redirectKinds сопоставляет операторам виды перенаправлений
+var redirectKinds = map[string]redirectKind{
+	"<":   redirectIn,
+	">":   redirectOut,
+	">>":  redirectAppend,
+	">&":  redirectDup,
+	"<<":  redirectHeredoc,
+	"<<-": redirectHeredoc,
+}
+
+// redirect — перенаправление дескриптора fd. target — имя файла, номер
+// дескриптора-источника для redirectDup или тело here-документа.
+type redirect struct {
+	kind   redirectKind
+	fd     int
+	target word
+}
+
+// command — простая команда: имя, аргументы и перенаправления, которые
+// применяются по порядку
+type command struct {
+	args      []word
+	redirects []*redirect
+}
+
+// pipeline — команды, связанные каналами "|"; background — конвейер
+// завершается "&" и выполняется в фоне
+type pipeline struct {
+	commands   []*command
+	background bool
+}
+
+// script — последовательность конвейеров, разделенных ";", "&" или переводом строки
+type script struct {
+	pipelines []*pipeline
+}
+
+// parser строит дерево команд из лексем
+type parser struct {
+	lex  *lexer
+	tok  token
+	err  error
+	peek bool // tok уже прочитан, но не обработан
+}
+
+func newParser(lex *lexer) *parser {
+	return &parser{lex: lex}
+}
+
+// parse разбирает командную строку
+func parse(input string) (*script, error) {
+	return newParser(&lexer{input: input}).parseScript()
+}
+
+// next возвращает очередную лексему, учитывая возвращенную через backup
+func (p *parser) next() (token, error) {
+	if p.peek {
+		p.peek = false
+		return p.tok, p.err
+	}
+	p.tok, p.err = p.lex.next()
+	return p.tok, p.err
+}
+
+// backup возвращает последнюю лексему, чтобы next выдал ее снова
+func (p *parser) backup() {
+	p.peek = true
+}
+
+// parseScript разбирает конвейеры до конца строки, а во вложенном лексере —
+// до закрывающей скобки подстановки
+func (p *parser) parseScript() (*script, error) {
+	s := &script{}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case tokenEOF:
+			if p.lex.nested {
+				return nil, fmt.Errorf("unterminated $(: %w", errIncomplete)
+			}
+			return s, nil
+		case tokenRParen:
+			return s, nil
+		case tokenSemicolon:
+			// Пустые команды между разделителями пропускаются
+			continue
+		}
+
+		p.backup()
+		pl, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		s.pipelines = append(s.pipelines, pl)
+
+		tok, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case tokenAmpersand:
+			pl.background = true
+		case tokenSemicolon:
+		default:
+			p.backup()
+		}
+	}
+}
+
+// parsePipeline разбирает команды, разделенные "|"
+func (p *parser) parsePipeline() (*pipeline, error) {
+	pl := &pipeline{}
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pl.commands = append(pl.commands, cmd)
+
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != tokenPipe {
+			p.backup()
+			return pl, nil
+		}
+	}
+}
+
+// parseCommand разбирает слова и перенаправления простой команды;
+// команда не может быть пустой
+func (p *parser) parseCommand() (*command, error) {
+	cmd := &command{}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case tokenWord:
+			cmd.args = append(cmd.args, tok.word)
+		case tokenRedirect:
+			r, err := p.parseRedirect(tok)
+			if err != nil {
+				return nil, err
+			}
+			cmd.redirects = append(cmd.redirects, r)
+		default:
+			if len(cmd.args) == 0 && len(cmd.redirects) == 0 {
+				return nil, unexpected(tok)
+			}
+			p.backup()
+			return cmd, nil
+		}
+	}
+}
+
+// parseRedirect разбирает цель перенаправления op. Для here-документа
+// тело читает лексер после перевода строки.
+func (p *parser) parseRedirect(op token) (*redirect, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if tok.kind != tokenWord {
+		return nil, unexpected(tok)
+	}
+
+	r := &redirect{kind: redirectKinds[op.text], fd: op.fd, target: tok.word}
+	if r.fd < 0 {
+		r.fd = 1
+		if op.text[0] == '<' {
+			r.fd = 0
+		}
+	}
+	if r.kind == redirectHeredoc {
+		delimiter, quoted := literalText(tok.word)
+		p.lex.heredocs = append(p.lex.heredocs, heredoc{
+			redirect:  r,
+			delimiter: delimiter,
+			expand:    !quoted,
+			stripTabs: op.text == "<<-",
+		})
+	}
+	return r, nil
+}
+
+// literalText возвращает исходный текст слова без кавычек — подстановки
+// в нем не выполняются — и сообщает, была ли в слове хоть одна часть в кавычках
+func literalText(w word) (string, bool) {
+	var text string
+	quoted := false
+	for _, part := range w {
+		quoted = quoted || part.quoted
+		switch part.kind {
+		case partVariable:
+			text += "$" + part.text
+		case partCommand:
+			text += "$(...)"
+		default:
+			text += part.text
+		}
+	}
+	return text, quoted
+}
+
+// unexpected возвращает ошибку разбора для лексемы tok
+func unexpected(tok token) error {
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("syntax error: %w", errIncomplete)
+	}
+	return fmt.Errorf("syntax error near unexpected token `%s'", tok.text)
+}
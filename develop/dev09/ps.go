@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procRoot — каталог файловой системы proc
+const procRoot = "/proc"
+
+// clockTicks — единица времени ЦП в /proc/[pid]/stat (USER_HZ). Без cgo
+// sysconf(_SC_CLK_TCK) недоступен, а на Linux это значение всегда 100.
+const clockTicks = 100
+
+// process — сведения о процессе из /proc/[pid]
+type process struct {
+	pid     int
+	ppid    int
+	state   string // R, S, D, Z, T и т. д.
+	rss     int64  // резидентная память в КБ
+	cpu     time.Duration
+	comm    string   // имя исполняемого файла
+	cmdline []string // аргументы; пусто у потоков ядра и зомби
+}
+
+// psColumn — колонка вывода ps
+type psColumn struct {
+	header string
+	width  int // ширина; числа выравниваются вправо, текст — влево
+	right  bool
+	value  func(p process) string
+}
+
+// psColumns — колонки ps по именам для -o
+var psColumns = map[string]psColumn{
+	"pid":  {header: "PID", width: 7, right: true, value: func(p process) string { return strconv.Itoa(p.pid) }},
+	"ppid": {header: "PPID", width: 7, right: true, value: func(p process) string { return strconv.Itoa(p.ppid) }},
+	"stat": {header: "S", width: 1, value: func(p process) string { return p.state }},
+	"rss":  {header: "RSS", width: 8, right: true, value: func(p process) string { return strconv.FormatInt(p.rss, 10) }},
+	"time": {header: "TIME", width: 8, right: true, value: func(p process) string { return formatCPU(p.cpu) }},
+	"comm": {header: "COMMAND", value: func(p process) string { return p.comm }},
+	"cmd":  {header: "CMD", value: commandLine},
+}
+
+// psAliases — другие имена колонок, принятые в ps
+var psAliases = map[string]string{
+	"state":   "stat",
+	"s":       "stat",
+	"rssize":  "rss",
+	"cputime": "time",
+	"ucomm":   "comm",
+	"command": "cmd",
+	"args":    "cmd",
+}
+
+// psDefault — колонки по умолчанию
+const psDefault = "pid,ppid,stat,rss,time,cmd"
+
+// ps печатает процессы, читая /proc:
+//
+//	ps [-e | -A] [-o колонки] [-p pid,...]
+//
+// По умолчанию выводятся все процессы с колонками PID, PPID, S, RSS (КБ),
+// TIME и CMD; -o выбирает колонки через запятую, -p — процессы.
+func (sh *shell) ps(args []string, std streams) int {
+	flags := flag.NewFlagSet("ps", flag.ContinueOnError)
+	flags.SetOutput(std.stderr)
+	flags.Bool("e", false, "все процессы (по умолчанию)")
+	flags.Bool("A", false, "все процессы (по умолчанию)")
+	format := flags.String("o", psDefault, "колонки через запятую: "+columnNames())
+	pidList := flags.String("p", "", "номера процессов через запятую")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 1
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(std.stderr, "ps: unexpected argument %q\n", flags.Arg(0))
+		return 1
+	}
+
+	columns, err := parseColumns(*format)
+	if err != nil {
+		fmt.Fprintln(std.stderr, "ps:", err)
+		return 1
+	}
+	var pids []int
+	if *pidList != "" {
+		for _, field := range strings.Split(*pidList, ",") {
+			pid, err := strconv.Atoi(field)
+			if err != nil || pid <= 0 {
+				fmt.Fprintf(std.stderr, "ps: invalid process ID %q\n", field)
+				return 1
+			}
+			pids = append(pids, pid)
+		}
+	}
+
+	processes, err := readProcesses(procRoot, pids)
+	if err != nil {
+		fmt.Fprintln(std.stderr, "ps:", err)
+		return 1
+	}
+	writeProcesses(std.stdout, columns, processes)
+	// Как ps, при -p возвращаем ошибку, если ни один процесс не найден
+	if len(pids) > 0 && len(processes) == 0 {
+		return 1
+	}
+	return 0
+}
+
+// columnNames перечисляет имена колонок для справки
+func columnNames() string {
+	names := make([]string, 0, len(psColumns))
+	for name := range psColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// parseColumns разбирает список колонок -o
+func parseColumns(format string) ([]psColumn, error) {
+	var columns []psColumn
+	for _, name := range strings.Split(format, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := psAliases[name]; ok {
+			name = alias
+		}
+		column, ok := psColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// writeProcesses печатает таблицу процессов. Последняя колонка не
+// дополняется пробелами.
+func writeProcesses(w io.Writer, columns []psColumn, processes []process) {
+	row := func(value func(column psColumn) string) {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			text := value(column)
+			switch {
+			case column.right:
+				text = fmt.Sprintf("%*s", column.width, text)
+			case i < len(columns)-1:
+				text = fmt.Sprintf("%-*s", column.width, text)
+			}
+			cells[i] = text
+		}
+		fmt.Fprintln(w, strings.Join(cells, " "))
+	}
+
+	row(func(column psColumn) string { return column.header })
+	for _, p := range processes {
+		row(func(column psColumn) string { return column.value(p) })
+	}
+}
+
+// readProcesses читает процессы из root по возрастанию pid. Если pids
+// не пуст, читаются только они. Процессы, завершившиеся во время чтения,
+// пропускаются.
+func readProcesses(root string, pids []int) ([]process, error) {
+	if len(pids) == 0 {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if pid, err := strconv.Atoi(entry.Name()); err == nil {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	sort.Ints(pids)
+
+	var processes []process
+	for _, pid := range pids {
+		p, err := readProcess(filepath.Join(root, strconv.Itoa(pid)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
+
+// readProcess читает stat и cmdline каталога процесса
+func readProcess(dir string) (process, error) {
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return process{}, err
+	}
+	p, err := parseStat(string(stat))
+	if err != nil {
+		return process{}, fmt.Errorf("%s: %w", dir, err)
+	}
+
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return process{}, err
+	}
+	if cmdline := strings.TrimRight(string(cmdline), "\x00"); cmdline != "" {
+		p.cmdline = strings.Split(cmdline, "\x00")
+	}
+	return p, nil
+}
+
+// parseStat разбирает /proc/[pid]/stat. Имя процесса в скобках может
+// содержать пробелы и скобки, поэтому поля отсчитываются от последней ")".
+func parseStat(stat string) (process, error) {
+	open := strings.IndexByte(stat, '(')
+	closing := strings.LastIndexByte(stat, ')')
+	if open < 0 || closing < open {
+		return process{}, errors.New("malformed stat")
+	}
+	// Поля после имени: 0 state, 1 ppid, ..., 11 utime, 12 stime, ..., 21 rss
+	fields := strings.Fields(stat[closing+1:])
+	if len(fields) < 22 {
+		return process{}, errors.New("malformed stat")
+	}
+
+	p := process{state: fields[0], comm: stat[open+1 : closing]}
+	var err error
+	if p.pid, err = strconv.Atoi(strings.TrimSpace(stat[:open])); err != nil {
+		return process{}, fmt.Errorf("malformed stat: %w", err)
+	}
+	numbers := make([]int64, 22)
+	for _, i := range []int{1, 11, 12, 21} {
+		if numbers[i], err = strconv.ParseInt(fields[i], 10, 64); err != nil {
+			return process{}, fmt.Errorf("malformed stat: %w", err)
+		}
+	}
+	p.ppid = int(numbers[1])
+	p.cpu = time.Duration(numbers[11]+numbers[12]) * time.Second / clockTicks
+	p.rss = numbers[21] * int64(os.Getpagesize()) / 1024
+	return p, nil
+}
+
+// commandLine возвращает аргументы процесса через пробел, а для процессов
+// без них — имя в квадратных скобках, как ps
+func commandLine(p process) string {
+	if len(p.cmdline) == 0 {
+		return "[" + p.comm + "]"
+	}
+	return strings.Join(p.cmdline, " ")
+}
+
+// formatCPU форматирует время ЦП как [ДД-]ЧЧ:ММ:СС
+func formatCPU(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	days, seconds := seconds/86400, seconds%86400
+	text := fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	if days > 0 {
+		text = fmt.Sprintf("%d-%s", days, text)
+	}
+	return text
+}
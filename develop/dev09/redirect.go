@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// streams — стандартные потоки команды: дескрипторы 0, 1 и 2
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// get возвращает поток дескриптора fd
+func (std *streams) get(fd int) (interface{}, error) {
+	switch fd {
+	case 0:
+		return std.stdin, nil
+	case 1:
+		return std.stdout, nil
+	case 2:
+		return std.stderr, nil
+	}
+	return nil, fmt.Errorf("%d: bad file descriptor", fd)
+}
+
+// set назначает дескриптору fd поток; вход должен читаться, выходы — записываться
+func (std *streams) set(fd int, stream interface{}) error {
+	switch fd {
+	case 0:
+		if r, ok := stream.(io.Reader); ok {
+			std.stdin = r
+			return nil
+		}
+	case 1, 2:
+		if w, ok := stream.(io.Writer); ok {
+			if fd == 1 {
+				std.stdout = w
+			} else {
+				std.stderr = w
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%d: bad file descriptor", fd)
+}
+
+// redirect применяет перенаправления к потокам std по порядку, так что
+// "2>&1 >file" и ">file 2>&1" различаются, как в sh. Открытые файлы
+// закрывает возвращаемая функция; при ошибке они уже закрыты.
+func (sh *shell) redirect(redirects []*redirect, std streams) (streams, func(), error) {
+	var files []*os.File
+	closeFiles := func() { closeAll(files...) }
+	for _, r := range redirects {
+		f, err := sh.applyRedirect(r, &std)
+		if f != nil {
+			files = append(files, f)
+		}
+		if err != nil {
+			closeFiles()
+			return streams{}, nil, err
+		}
+	}
+	return std, closeFiles, nil
+}
+
+// applyRedirect применяет одно перенаправление и возвращает открытый для него файл
+func (sh *shell) applyRedirect(r *redirect, std *streams) (*os.File, error) {
+	if r.kind == redirectHeredoc {
+		body := strings.Join(sh.expandWord(r.target), "")
+		return nil, std.set(r.fd, strings.NewReader(body))
+	}
+
+	targets := sh.expandWord(r.target)
+	if len(targets) != 1 {
+		text, _ := literalText(r.target)
+		return nil, fmt.Errorf("%s: ambiguous redirect", text)
+	}
+	target := targets[0]
+
+	var f *os.File
+	var err error
+	switch r.kind {
+	case redirectDup:
+		source, err := strconv.Atoi(target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad file descriptor", target)
+		}
+		stream, err := std.get(source)
+		if err != nil {
+			return nil, err
+		}
+		return nil, std.set(r.fd, stream)
+	case redirectIn:
+		f, err = os.Open(sh.path(target))
+	case redirectOut:
+		f, err = os.Create(sh.path(target))
+	case redirectAppend:
+		f, err = os.OpenFile(sh.path(target), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, std.set(r.fd, f)
+}
@@ -14,21 +14,39 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 )
 
 func main() {
+	sh := newShell(os.Stdin, os.Stdout, os.Stderr)
+	sh.enableJobControl(os.Stdin)
+	scanner := bufio.NewScanner(os.Stdin)
+	// pending — начало команды, оборвавшейся внутри кавычек, подстановки
+	// или после "|"; она продолжается следующей строкой
+	var pending string
 	for {
-		// Вывод приглашения
-		fmt.Print("myshell> ")
+		// Вывод приглашения и уведомлений о фоновых заданиях
+		if pending == "" {
+			sh.reportJobs(os.Stderr, false)
+			fmt.Print("myshell> ")
+		} else {
+			fmt.Print("> ")
+		}
 
 		// Чтение ввода пользователя
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		input := scanner.Text()
+		if !scanner.Scan() {
+			fmt.Println()
+			break
+		}
+		input := pending + scanner.Text()
 
 		// Если введено \quit, выходим из программы
 		if input == "\\quit" {
@@ -36,106 +54,269 @@ func main() {
 		}
 
 		// Парсим команду
-		args := strings.Fields(input)
+		s, err := parse(input)
+		if errors.Is(err, errIncomplete) {
+			pending = input + "\n"
+			continue
+		}
+		pending = ""
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "myshell:", err)
+			sh.status = 2
+			continue
+		}
+		sh.run(s)
+	}
+}
+
+// shell — состояние интерпретатора: стандартные потоки, которые команды
+// получают по умолчанию, код возврата последней команды для $? и задания
+type shell struct {
+	streams
+	status int
+	dir    string // рабочий каталог подоболочки; пусто — текущий каталог процесса
+
+	tty  int    // дескриптор управляющего терминала, -1 без управления терминалом
+	pgid int    // группа процессов шелла, которой возвращается терминал
+	jobs []*job // фоновые и остановленные задания по возрастанию номеров
+}
+
+func newShell(stdin io.Reader, stdout, stderr io.Writer) *shell {
+	std := streams{stdin: stdin, stdout: syncWriter(stdout), stderr: syncWriter(stderr)}
+	return &shell{streams: std, tty: -1}
+}
+
+// subshell возвращает копию шелла для подстановки $(...) и встроенных
+// команд конвейеров и фоновых заданий: ее cd меняет
+// только собственный каталог копии, а задания копии отдельны от заданий
+// шелла, поэтому выполненные в ней команды не меняют его состояние
+func (sh *shell) subshell() *shell {
+	dir, err := sh.getwd()
+	if err != nil {
+		dir = "."
+	}
+	return &shell{streams: sh.streams, status: sh.status, dir: dir, tty: -1}
+}
+
+// getwd возвращает рабочий каталог шелла
+func (sh *shell) getwd() (string, error) {
+	if sh.dir != "" {
+		return sh.dir, nil
+	}
+	return os.Getwd()
+}
+
+// chdir меняет рабочий каталог шелла. Подоболочка только проверяет, что
+// каталог существует, и запоминает его, не трогая каталог процесса.
+func (sh *shell) chdir(name string) error {
+	if sh.dir == "" {
+		return os.Chdir(name)
+	}
+	dir := sh.path(name)
+	info, err := os.Stat(dir)
+	if err == nil && !info.IsDir() {
+		err = syscall.ENOTDIR
+	}
+	if err != nil {
+		if pathErr, ok := err.(*os.PathError); ok {
+			err = pathErr.Err
+		}
+		return &os.PathError{Op: "chdir", Path: name, Err: err}
+	}
+	sh.dir = dir
+	return nil
+}
+
+// path разрешает относительный путь от рабочего каталога шелла
+func (sh *shell) path(name string) string {
+	if sh.dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(sh.dir, name)
+}
+
+// run выполняет конвейеры по очереди
+func (sh *shell) run(s *script) {
+	for _, pl := range s.pipelines {
+		sh.status = sh.runPipeline(pl)
+	}
+}
+
+// runPipeline запускает конвейер и возвращает код возврата последней команды.
+// Одиночная команда переднего плана может быть встроенной и менять
+// состояние шелла; остальные конвейеры становятся заданиями.
+func (sh *shell) runPipeline(pl *pipeline) int {
+	if len(pl.commands) == 1 && !pl.background {
+		return sh.runCommand(pl.commands[0])
+	}
+	return sh.handlePipe(pl)
+}
 
+// runCommand выполняет подстановки и перенаправления команды, а затем
+// встроенную или внешнюю команду. Команда без аргументов только создает
+// файлы перенаправлений.
+func (sh *shell) runCommand(cmd *command) int {
+	args := sh.expandWords(cmd.args)
+	std, closeFiles, err := sh.redirect(cmd.redirects, sh.streams)
+	if err != nil {
+		fmt.Fprintln(sh.stderr, "myshell:", err)
+		return 1
+	}
+	defer closeFiles()
+
+	if len(args) == 0 {
+		return 0
+	}
+	if builtin, ok := builtins[args[0]]; ok {
+		return builtin(sh, args, std)
+	}
+	return sh.handleSimpleCommand(args, std)
+}
+
+// handlePipe запускает команды конвейера как одно задание, соединяя вывод
+// каждой со входом следующей, и возвращает код возврата последней или 0
+// для фонового задания. Встроенные команды конвейера выполняются
+// в горутинах, каждая в своей подоболочке.
+func (sh *shell) handlePipe(pl *pipeline) int {
+	commands := pl.commands
+
+	// Связываем пайпы: readers[i] — вход i-й команды, writers[i] — ее выход.
+	// Шелл закрывает свои копии концов, как только внешняя команда запущена
+	// или встроенная завершилась, иначе читатель не дождется конца ввода.
+	readers := make([]*os.File, len(commands))
+	writers := make([]*os.File, len(commands))
+	for i := 0; i < len(commands)-1; i++ {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			fmt.Fprintln(sh.stderr, "myshell:", err)
+			closeAll(readers...)
+			closeAll(writers...)
+			return 1
+		}
+		writers[i], readers[i+1] = pw, pr
+	}
+
+	j := newJob(len(commands), !pl.background)
+	texts := make([]string, len(commands))
+	for i, cmd := range commands {
+		std := sh.streams
+		if pl.background && sh.tty < 0 {
+			// Фоновые задания без управления терминалом не читают ввод шелла
+			std.stdin = strings.NewReader("")
+		}
+		if readers[i] != nil {
+			std.stdin = readers[i]
+		}
+		if writers[i] != nil {
+			std.stdout = writers[i]
+		}
+
+		args := sh.expandWords(cmd.args)
+		texts[i] = strings.Join(args, " ")
+		std, closeFiles, err := sh.redirect(cmd.redirects, std)
+		if err != nil {
+			fmt.Fprintln(sh.stderr, "myshell:", err)
+			j.setStatus(i, 1)
+			closeAll(readers[i], writers[i])
+			continue
+		}
+		ends := []*os.File{readers[i], writers[i]}
+		release := func() {
+			closeAll(ends...)
+			closeFiles()
+		}
 		if len(args) == 0 {
+			release()
 			continue
 		}
 
-		// Обработка команд
-		switch args[0] {
-		case "cd":
-			if len(args) < 2 {
-				fmt.Println("cd: missing argument")
-			} else {
-				if err := os.Chdir(args[1]); err != nil {
-					fmt.Println("cd:", err)
-				}
-			}
-		case "pwd":
-			dir, err := os.Getwd()
-			if err != nil {
-				fmt.Println("pwd:", err)
-			} else {
-				fmt.Println(dir)
-			}
-		case "echo":
-			fmt.Println(strings.Join(args[1:], " "))
-		case "kill":
-			if len(args) < 2 {
-				fmt.Println("kill: missing argument")
-			} else {
-				pid := args[1]
-				cmd := exec.Command("kill", pid)
-				if err := cmd.Run(); err != nil {
-					fmt.Println("kill:", err)
-				}
-			}
-		case "ps":
-			cmd := exec.Command("ps", "-aux")
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			if err := cmd.Run(); err != nil {
-				fmt.Println("ps:", err)
-			}
-		default:
-			// Обработка пайпа
-			commands := strings.Split(input, "|")
-			if len(commands) > 1 {
-				handlePipe(commands)
-			} else {
-				handleSimpleCommand(args)
-			}
-		}
-	}
-}
-
-// Функция для обработки команд с пайпами
-func handlePipe(commands []string) {
-	var cmdArr []*exec.Cmd
-	for _, command := range commands {
-		args := strings.Fields(strings.TrimSpace(command))
-		if len(args) > 0 {
-			cmdArr = append(cmdArr, exec.Command(args[0], args[1:]...))
-		}
-	}
-
-	// Связываем пайпы
-	for i := 0; i < len(cmdArr)-1; i++ {
-		pr, pw := pipe()
-		cmdArr[i].Stdout = pw
-		cmdArr[i+1].Stdin = pr
-	}
-
-	// Выполняем все команды
-	for _, cmd := range cmdArr {
-		if err := cmd.Start(); err != nil {
-			fmt.Println("Error:", err)
-			return
+		// Выполняем команду
+		if builtin, ok := builtins[args[0]]; ok {
+			sub := sh.subshell()
+			j.goBuiltin(i, func() int {
+				defer release()
+				return builtin(sub, args, std)
+			})
+			continue
+		}
+		if err := j.start(sh, i, sh.command(args, std)); err != nil {
+			fmt.Fprintln(sh.stderr, "myshell:", err)
+			j.setStatus(i, exitStatus(err))
 		}
+		release()
 	}
+	j.text = strings.Join(texts, " | ")
 
 	// Ждем завершения всех команд
-	for _, cmd := range cmdArr {
-		cmd.Wait()
+	return sh.runJob(j)
+}
+
+// handleSimpleCommand выполняет одну внешнюю команду как задание переднего плана
+func (sh *shell) handleSimpleCommand(args []string, std streams) int {
+	j := newJob(1, true)
+	j.text = strings.Join(args, " ")
+	if err := j.start(sh, 0, sh.command(args, std)); err != nil {
+		fmt.Fprintln(std.stderr, "myshell:", err)
+		return exitStatus(err)
 	}
+	return sh.runJob(j)
 }
 
-// Функция для выполнения одной команды
-func handleSimpleCommand(args []string) {
+// command готовит запуск внешней команды с потоками std
+func (sh *shell) command(args []string, std streams) *exec.Cmd {
 	cmd := exec.Command(args[0], args[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		fmt.Println("Error:", err)
+	cmd.Dir = sh.dir
+	cmd.Stdin = std.stdin
+	cmd.Stdout = std.stdout
+	cmd.Stderr = std.stderr
+	return cmd
+}
+
+// closeAll закрывает файлы, пропуская nil
+func closeAll(files ...*os.File) {
+	for _, f := range files {
+		if f != nil {
+			f.Close()
+		}
 	}
 }
 
-// Функция для создания pipe
-func pipe() (*os.File, *os.File) {
-	r, w, err := os.Pipe()
-	if err != nil {
-		fmt.Println("Error creating pipe:", err)
+// syncWriter защищает поток вывода, который не является файлом: в него
+// exec копирует вывод команд из своих горутин, и они пишут одновременно
+// друг с другом и с шеллом — например, пока задание остановлено
+func syncWriter(w io.Writer) io.Writer {
+	if _, ok := w.(*os.File); ok {
+		return w
+	}
+	return &lockedWriter{w: w}
+}
+
+// lockedWriter сериализует запись в общий поток
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
+
+// exitStatus возвращает код возврата команды, которую не удалось
+// запустить: 127, если она не найдена, и 126 в остальных случаях
+func exitStatus(err error) int {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return 127
+	}
+	return 126
+}
+
+// waitStatus возвращает код возврата завершившегося процесса:
+// 128+N для завершения сигналом N
+func waitStatus(ws syscall.WaitStatus) int {
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
 	}
-	return r, w
+	return ws.ExitStatus()
 }
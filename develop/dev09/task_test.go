@@ -1 +1,806 @@
 package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Конструкторы частей слов для ожидаемых деревьев разбора
+func lit(text string) wordPart  { return wordPart{kind: partLiteral, text: text} }
+func qlit(text string) wordPart { return wordPart{kind: partLiteral, text: text, quoted: true} }
+func variable(name string, quoted bool) wordPart {
+	return wordPart{kind: partVariable, text: name, quoted: quoted}
+}
+
+// simple строит конвейер из одной команды
+func simple(args ...word) *pipeline {
+	return &pipeline{commands: []*command{{args: args}}}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []*pipeline
+	}{
+		{
+			name:  "Empty line",
+			input: "  ",
+		},
+		{
+			name:  "Plain words",
+			input: "ls  -l\t/tmp",
+			want:  []*pipeline{simple(word{lit("ls")}, word{lit("-l")}, word{lit("/tmp")})},
+		},
+		{
+			name:  "Pipe inside quotes",
+			input: `echo "a | b"`,
+			want:  []*pipeline{simple(word{lit("echo")}, word{qlit("a | b")})},
+		},
+		{
+			name:  "Single quotes keep everything literal",
+			input: `grep 'x $y \z'`,
+			want:  []*pipeline{simple(word{lit("grep")}, word{qlit(`x $y \z`)})},
+		},
+		{
+			name:  "Empty quotes are an argument",
+			input: `echo "" ''`,
+			want:  []*pipeline{simple(word{lit("echo")}, word{qlit("")}, word{qlit("")})},
+		},
+		{
+			name:  "Adjacent parts form one word",
+			input: `a'b'"c"d`,
+			want:  []*pipeline{simple(word{lit("a"), qlit("b"), qlit("c"), lit("d")})},
+		},
+		{
+			name:  "Backslash escapes",
+			input: `echo a\ b \| \$X \\`,
+			want:  []*pipeline{simple(word{lit("echo")}, word{lit("a b")}, word{lit("|")}, word{lit("$X")}, word{lit(`\`)})},
+		},
+		{
+			name:  "Backslash in double quotes",
+			input: `echo "\"\$\\\n"`,
+			want:  []*pipeline{simple(word{lit("echo")}, word{qlit(`"$\\n`)})},
+		},
+		{
+			name:  "Variables",
+			input: `echo $HOME ${USER}x "$?" $$ $ $1`,
+			want: []*pipeline{simple(
+				word{lit("echo")},
+				word{variable("HOME", false)},
+				word{variable("USER", false), lit("x")},
+				word{variable("?", true)},
+				word{variable("$", false)},
+				word{lit("$")},
+				word{lit("$1")},
+			)},
+		},
+		{
+			name:  "Variable inside double quotes",
+			input: `echo "home: $HOME!"`,
+			want:  []*pipeline{simple(word{lit("echo")}, word{qlit("home: "), variable("HOME", true), qlit("!")})},
+		},
+		{
+			name:  "Pipeline",
+			input: "cat file|grep x | wc -l",
+			want: []*pipeline{{commands: []*command{
+				{args: []word{{lit("cat")}, {lit("file")}}},
+				{args: []word{{lit("grep")}, {lit("x")}}},
+				{args: []word{{lit("wc")}, {lit("-l")}}},
+			}}},
+		},
+		{
+			name:  "Command list",
+			input: "cd /; pwd;;\necho # comment | ignored",
+			want: []*pipeline{
+				simple(word{lit("cd")}, word{lit("/")}),
+				simple(word{lit("pwd")}),
+				simple(word{lit("echo")}),
+			},
+		},
+		{
+			name:  "Background",
+			input: "sleep 1 | cat & echo x&",
+			want: []*pipeline{
+				{commands: []*command{
+					{args: []word{{lit("sleep")}, {lit("1")}}},
+					{args: []word{{lit("cat")}}},
+				}, background: true},
+				{commands: []*command{{args: []word{{lit("echo")}, {lit("x")}}}}, background: true},
+			},
+		},
+		{
+			name:  "Redirections",
+			input: "cmd <in >out 2>>err x 2>&1",
+			want: []*pipeline{{commands: []*command{{
+				args: []word{{lit("cmd")}, {lit("x")}},
+				redirects: []*redirect{
+					{kind: redirectIn, fd: 0, target: word{lit("in")}},
+					{kind: redirectOut, fd: 1, target: word{lit("out")}},
+					{kind: redirectAppend, fd: 2, target: word{lit("err")}},
+					{kind: redirectDup, fd: 2, target: word{lit("1")}},
+				},
+			}}}},
+		},
+		{
+			name:  "Redirection without spaces and digits in words",
+			input: `echo a2>"$F" 2 > f`,
+			want: []*pipeline{{commands: []*command{{
+				args: []word{{lit("echo")}, {lit("a2")}, {lit("2")}},
+				redirects: []*redirect{
+					{kind: redirectOut, fd: 1, target: word{variable("F", true)}},
+					{kind: redirectOut, fd: 1, target: word{lit("f")}},
+				},
+			}}}},
+		},
+		{
+			name:  "Redirection only",
+			input: ">file",
+			want: []*pipeline{{commands: []*command{{
+				redirects: []*redirect{{kind: redirectOut, fd: 1, target: word{lit("file")}}},
+			}}}},
+		},
+		{
+			name:  "Here-documents",
+			input: "cat <<EOF | cat - <<-'END'\nhello $USER\n\\$x\nEOF\n\tliteral $USER\n\tEND\necho",
+			want: []*pipeline{
+				{commands: []*command{
+					{
+						args: []word{{lit("cat")}},
+						redirects: []*redirect{{kind: redirectHeredoc, fd: 0, target: word{
+							qlit("hello "), variable("USER", true), qlit("\n$x\n"),
+						}}},
+					},
+					{
+						args:      []word{{lit("cat")}, {lit("-")}},
+						redirects: []*redirect{{kind: redirectHeredoc, fd: 0, target: word{qlit("literal $USER\n")}}},
+					},
+				}},
+				simple(word{lit("echo")}),
+			},
+		},
+		{
+			name:  "Command substitution",
+			input: `echo $(echo "a)" | tr a b)x`,
+			want: []*pipeline{simple(
+				word{lit("echo")},
+				word{
+					{kind: partCommand, script: &script{pipelines: []*pipeline{{commands: []*command{
+						{args: []word{{lit("echo")}, {qlit("a)")}}},
+						{args: []word{{lit("tr")}, {lit("a")}, {lit("b")}}},
+					}}}}},
+					lit("x"),
+				},
+			)},
+		},
+		{
+			name:  "Nested substitution in quotes",
+			input: `echo "$(basename $(pwd))"`,
+			want: []*pipeline{simple(
+				word{lit("echo")},
+				word{{kind: partCommand, quoted: true, script: &script{pipelines: []*pipeline{simple(
+					word{lit("basename")},
+					word{{kind: partCommand, script: &script{pipelines: []*pipeline{simple(word{lit("pwd")})}}}},
+				)}}}},
+			)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := parse(test.input)
+			if err != nil {
+				t.Fatalf("parse(%q) error: %v", test.input, err)
+			}
+			if !reflect.DeepEqual(s.pipelines, test.want) {
+				t.Errorf("parse(%q) = %s, want %s", test.input, dump(s.pipelines), dump(test.want))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{input: `echo 'a`, incomplete: true},
+		{input: `echo "a`, incomplete: true},
+		{input: `echo $(ls`, incomplete: true},
+		{input: `echo ${HOME`, incomplete: true},
+		{input: `ls |`, incomplete: true},
+		{input: `| ls`},
+		{input: `ls | | wc`},
+		{input: `echo )`},
+		{input: `echo (a)`},
+		{input: `echo ${1x}`},
+		{input: `echo $(|)`},
+		{input: "cat <<EOF\nbody", incomplete: true},
+		{input: "cat <<EOF", incomplete: true},
+		{input: `echo >`, incomplete: true},
+		{input: `echo > | cat`},
+		{input: `echo 99999999999999999999>x`},
+		{input: `sleep 1 && echo`},
+		{input: `& echo`},
+	}
+
+	for _, test := range tests {
+		_, err := parse(test.input)
+		if err == nil {
+			t.Errorf("parse(%q) expected error", test.input)
+			continue
+		}
+		if got := errors.Is(err, errIncomplete); got != test.incomplete {
+			t.Errorf("parse(%q) error %q: incomplete = %v, want %v", test.input, err, got, test.incomplete)
+		}
+	}
+}
+
+// dump печатает конвейеры для сообщений об ошибках
+func dump(pipelines []*pipeline) string {
+	var b strings.Builder
+	for _, pl := range pipelines {
+		for i, cmd := range pl.commands {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString("[")
+			for _, w := range cmd.args {
+				b.WriteString(" {")
+				for _, part := range w {
+					if part.kind == partCommand {
+						b.WriteString(" $(" + dump(part.script.pipelines) + ")")
+						continue
+					}
+					b.WriteString(" ")
+					if part.quoted {
+						b.WriteString("q")
+					}
+					b.WriteString("`" + part.text + "`")
+				}
+				b.WriteString(" }")
+			}
+			for _, r := range cmd.redirects {
+				fmt.Fprintf(&b, " %d:%d%s", r.fd, r.kind, dump([]*pipeline{simple(r.target)}))
+			}
+			b.WriteString(" ]")
+		}
+		b.WriteString("; ")
+	}
+	return b.String()
+}
+
+func TestExpandWord(t *testing.T) {
+	t.Setenv("SPACED", "  a b  ")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{input: `$SPACED`, want: []string{"a", "b"}},
+		{input: `"$SPACED"`, want: []string{"  a b  "}},
+		{input: `x$SPACED`, want: []string{"x", "a", "b"}},
+		{input: `x${SPACED}y`, want: []string{"x", "a", "b", "y"}},
+		{input: `$EMPTY`},
+		{input: `$UNSET_VARIABLE_FOR_TEST`},
+		{input: `"$EMPTY"`, want: []string{""}},
+		{input: `a$EMPTY`, want: []string{"a"}},
+		{input: `$(echo one two)`, want: []string{"one", "two"}},
+		{input: `"$(echo one two)"`, want: []string{"one two"}},
+		{input: `'$SPACED'`, want: []string{"$SPACED"}},
+	}
+
+	sh := newShell(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+	for _, test := range tests {
+		s, err := parse(test.input)
+		if err != nil {
+			t.Fatalf("parse(%q) error: %v", test.input, err)
+		}
+		got := sh.expandWord(s.pipelines[0].commands[0].args[0])
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expandWord(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("GREETING", "hello world")
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Echo with quotes", input: `echo "a | b"  'c  d'`, want: "a | b c  d\n"},
+		{name: "Variable splitting", input: `printf '<%s>' $GREETING "$GREETING"`, want: "<hello><world><hello world>"},
+		{name: "Exit status", input: `false; echo $?; true; echo $?`, want: "1\n0\n"},
+		{name: "Command not found", input: `no-such-command-for-test; echo $?`, want: "127\n"},
+		{name: "Pipeline", input: `echo "a b c" | tr ' ' '\n' | wc -l`, want: "3\n"},
+		{name: "Pipeline status", input: `echo x | false; echo $?`, want: "1\n"},
+		{name: "Builtin substitution", input: `echo "[$(pwd)]"`, want: "[" + dir + "]\n"},
+		{name: "External substitution", input: `echo $(printf 'x\n\n')y`, want: "xy\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := parse(test.input)
+			if err != nil {
+				t.Fatalf("parse(%q) error: %v", test.input, err)
+			}
+			var stdout, stderr bytes.Buffer
+			sh := newShell(strings.NewReader(""), &stdout, &stderr)
+			sh.run(s)
+			if got := stdout.String(); got != test.want {
+				t.Errorf("run(%q) = %q, want %q (stderr %q)", test.input, got, test.want, stderr.String())
+			}
+		})
+	}
+}
+
+// Подоболочка не должна менять каталог и задания шелла
+func TestSubshell(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DIR", dir)
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+		stderr string
+		jobs   []int // коды возврата фоновых заданий
+	}{
+		{
+			name:   "Substitution cd",
+			input:  `cd /; echo $(cd $DIR; pwd); pwd`,
+			stdout: dir + "\n/\n",
+		},
+		{
+			name:   "Relative paths in substitution",
+			input:  `cd /; echo $(cd $DIR; cd sub; echo x > file; cat file; ls ..)`,
+			stdout: "x sub\n",
+		},
+		{
+			name:   "Substitution cd error",
+			input:  `cd /; echo $(cd $DIR/missing; pwd); echo $(echo > $DIR/plain; cd $DIR/plain)`,
+			stdout: "/\n\n",
+			stderr: "cd: chdir " + dir + "/missing: no such file or directory\n" +
+				"cd: chdir " + dir + "/plain: not a directory\n",
+		},
+		{
+			name:   "Pipeline cd",
+			input:  `cd /; cd $DIR | cat; pwd; cd $DIR/sub; pwd | cat`,
+			stdout: "/\n" + dir + "/sub\n",
+		},
+		{
+			name:   "Background cd",
+			input:  `cd /; cd $DIR & pwd`,
+			stdout: "/\n",
+			stderr: "[1]\n",
+			jobs:   []int{0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(dir)
+			s, err := parse(test.input)
+			if err != nil {
+				t.Fatalf("parse(%q) error: %v", test.input, err)
+			}
+			var stdout, stderr bytes.Buffer
+			sh := newShell(strings.NewReader(""), &stdout, &stderr)
+			sh.run(s)
+
+			// Потоки читаются только после завершения фоновых заданий
+			var jobs []int
+			for _, j := range sh.jobs {
+				waitState(t, j, jobDone)
+				_, status := j.current()
+				jobs = append(jobs, status)
+			}
+			if !reflect.DeepEqual(jobs, test.jobs) {
+				t.Errorf("run(%q) jobs = %v, want %v", test.input, jobs, test.jobs)
+			}
+			if got := stdout.String(); got != test.stdout {
+				t.Errorf("run(%q) stdout = %q, want %q", test.input, got, test.stdout)
+			}
+			if got := stderr.String(); got != test.stderr {
+				t.Errorf("run(%q) stderr = %q, want %q", test.input, got, test.stderr)
+			}
+		})
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DIR", dir)
+	t.Setenv("NAME", "world")
+	t.Setenv("SPACED", "a b")
+
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+		stderr string
+		files  map[string]string
+	}{
+		{
+			name:   "Builtin output to file",
+			input:  `echo hello > $DIR/out; echo again >> "$DIR/out"; cat < $DIR/out`,
+			stdout: "hello\nagain\n",
+			files:  map[string]string{"out": "hello\nagain\n"},
+		},
+		{
+			name:  "Truncate",
+			input: `echo long line > $DIR/out; echo short >$DIR/out`,
+			files: map[string]string{"out": "short\n"},
+		},
+		{
+			name:  "Redirection only creates file",
+			input: `> $DIR/empty`,
+			files: map[string]string{"empty": ""},
+		},
+		{
+			name:   "Stderr to file",
+			input:  `ls $DIR/missing 2> $DIR/err; echo $?`,
+			stdout: "2\n",
+		},
+		{
+			name:   "Stderr to stdout in order",
+			input:  `sh -c 'echo out; echo err >&2' 2>&1 >$DIR/out | tr a-z A-Z`,
+			stdout: "ERR\n",
+			files:  map[string]string{"out": "out\n"},
+		},
+		{
+			name:   "Builtin stderr to stdout",
+			input:  `cd 2>&1 | tr a-z A-Z`,
+			stdout: "CD: MISSING ARGUMENT\n",
+		},
+		{
+			name:   "Pipeline stages",
+			input:  `pwd >$DIR/pwd | echo first | cat > $DIR/first; cat $DIR/first`,
+			stdout: "first\n",
+		},
+		{
+			name:   "Builtin in pipeline",
+			input:  `echo a b c | tr ' ' '\n' | wc -l`,
+			stdout: "3\n",
+		},
+		{
+			name:   "Here-document",
+			input:  "cat <<EOF\nhello $NAME\n\\$NAME\nEOF\ncat <<'EOF'\n$NAME\nEOF",
+			stdout: "hello world\n$NAME\n$NAME\n",
+		},
+		{
+			name:   "Missing input file",
+			input:  `cat < $DIR/missing; echo $?`,
+			stdout: "1\n",
+			stderr: "myshell: open " + filepath.Join(dir, "missing") + ": no such file or directory\n",
+		},
+		{
+			name:   "Ambiguous redirect",
+			input:  `echo x > $SPACED; echo $?`,
+			stdout: "1\n",
+			stderr: "myshell: $SPACED: ambiguous redirect\n",
+		},
+		{
+			name:   "Bad file descriptor",
+			input:  `echo x 3>$DIR/three; echo x >&5`,
+			stderr: "myshell: 3: bad file descriptor\nmyshell: 5: bad file descriptor\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := parse(test.input)
+			if err != nil {
+				t.Fatalf("parse(%q) error: %v", test.input, err)
+			}
+			var stdout, stderr bytes.Buffer
+			sh := newShell(strings.NewReader(""), &stdout, &stderr)
+			sh.run(s)
+			if got := stdout.String(); got != test.stdout {
+				t.Errorf("run(%q) stdout = %q, want %q", test.input, got, test.stdout)
+			}
+			if test.stderr != "" && stderr.String() != test.stderr {
+				t.Errorf("run(%q) stderr = %q, want %q", test.input, stderr.String(), test.stderr)
+			}
+			for name, want := range test.files {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != want {
+					t.Errorf("run(%q) %s = %q, want %q", test.input, name, data, want)
+				}
+			}
+		})
+	}
+}
+
+// runInput разбирает и выполняет строку в шелле sh
+func runInput(t *testing.T, sh *shell, input string) {
+	t.Helper()
+	s, err := parse(input)
+	if err != nil {
+		t.Fatalf("parse(%q) error: %v", input, err)
+	}
+	sh.run(s)
+}
+
+// waitState ждет, пока задание перейдет в состояние want
+func waitState(t *testing.T, j *job, want jobState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if state, _ := j.current(); state == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %q did not become %s", j.text, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DIR", dir)
+	var stdout, stderr bytes.Buffer
+	sh := newShell(strings.NewReader(""), &stdout, &stderr)
+
+	runInput(t, sh, `sleep 0.2 > $DIR/out & echo started; cat /dev/null | sh -c 'exit 3' &`)
+	if len(sh.jobs) != 2 {
+		t.Fatalf("jobs = %d, want 2", len(sh.jobs))
+	}
+	first, second := sh.jobs[0], sh.jobs[1]
+	if want := fmt.Sprintf("[1] %d\n[2] %d\n", first.pgid, second.pgid); stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+	if stdout.String() != "started\n" || sh.status != 0 {
+		t.Errorf("stdout = %q, status %d", stdout.String(), sh.status)
+	}
+
+	waitState(t, second, jobDone)
+	stdout.Reset()
+	runInput(t, sh, `jobs`)
+	want := "[1]  Running  sleep 0.2\n[2]  Exit 3  cat /dev/null | sh -c exit 3\n"
+	if stdout.String() != want {
+		t.Errorf("jobs = %q, want %q", stdout.String(), want)
+	}
+
+	// Завершившееся задание убрано из таблицы, о нем больше не сообщается
+	waitState(t, first, jobDone)
+	var report bytes.Buffer
+	sh.reportJobs(&report, false)
+	if want := "[1]  Done  sleep 0.2\n"; report.String() != want {
+		t.Errorf("report = %q, want %q", report.String(), want)
+	}
+	report.Reset()
+	sh.reportJobs(&report, false)
+	if report.Len() != 0 || len(sh.jobs) != 0 {
+		t.Errorf("second report = %q, jobs %d", report.String(), len(sh.jobs))
+	}
+}
+
+func TestStopAndContinue(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	sh := newShell(strings.NewReader(""), &stdout, &stderr)
+
+	// Команда переднего плана останавливает саму себя, как после Ctrl+Z
+	runInput(t, sh, `sh -c 'kill -STOP $$; exit 4'`)
+	if sh.status != 128+int(syscall.SIGTSTP) || len(sh.jobs) != 1 {
+		t.Fatalf("status = %d, jobs %d", sh.status, len(sh.jobs))
+	}
+	if want := "\n[1]  Stopped  sh -c kill -STOP $$; exit 4\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+
+	stdout.Reset()
+	runInput(t, sh, `fg %1`)
+	if sh.status != 4 || len(sh.jobs) != 0 {
+		t.Errorf("fg status = %d, jobs %d", sh.status, len(sh.jobs))
+	}
+	if want := "sh -c kill -STOP $$; exit 4\n"; stdout.String() != want {
+		t.Errorf("fg stdout = %q, want %q", stdout.String(), want)
+	}
+
+	// Фоновое задание останавливается и продолжается всей группой
+	runInput(t, sh, `sleep 10 | sleep 10 &`)
+	j := sh.jobs[0]
+	if err := syscall.Kill(-j.pgid, syscall.SIGSTOP); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, j, jobStopped)
+	stdout.Reset()
+	runInput(t, sh, `bg; jobs`)
+	if want := "[1] sleep 10 | sleep 10 &\n[1]  Running  sleep 10 | sleep 10\n"; stdout.String() != want {
+		t.Errorf("bg stdout = %q, want %q", stdout.String(), want)
+	}
+	if err := syscall.Kill(-j.pgid, syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	runInput(t, sh, `fg`)
+	if want := 128 + int(syscall.SIGTERM); sh.status != want {
+		t.Errorf("fg status = %d, want %d", sh.status, want)
+	}
+
+	stderr.Reset()
+	runInput(t, sh, `fg %3; bg`)
+	if want := "fg: %3: no such job\nbg: no current job\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		input string
+		want  syscall.Signal
+		err   bool
+	}{
+		{input: "9", want: syscall.SIGKILL},
+		{input: "0", want: 0},
+		{input: "KILL", want: syscall.SIGKILL},
+		{input: "sigterm", want: syscall.SIGTERM},
+		{input: "SIGCONT", want: syscall.SIGCONT},
+		{input: "Usr1", want: syscall.SIGUSR1},
+		{input: "65", err: true},
+		{input: "SIGNOPE", err: true},
+		{input: "", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseSignal(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("parseSignal(%q) error = %v, want error %v", test.input, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseSignal(%q) = %d, want %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestKill(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	sh := newShell(strings.NewReader(""), &stdout, &stderr)
+
+	// Остановленное задание после SIGTERM продолжается и завершается
+	runInput(t, sh, `sleep 10 & kill -STOP %1`)
+	j := sh.jobs[0]
+	waitState(t, j, jobStopped)
+	runInput(t, sh, `kill %1`)
+	waitState(t, j, jobDone)
+	if _, status := j.current(); status != 128+int(syscall.SIGTERM) {
+		t.Errorf("status after kill %%1 = %d", status)
+	}
+
+	runInput(t, sh, `sleep 10 &`)
+	j = sh.jobs[len(sh.jobs)-1]
+	runInput(t, sh, fmt.Sprintf(`kill -s KILL %d`, j.pgid))
+	waitState(t, j, jobDone)
+	if _, status := j.current(); status != 128+int(syscall.SIGKILL) {
+		t.Errorf("status after kill -s KILL = %d", status)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	runInput(t, sh, `kill -l 143 9 hup; kill -9 %7 x; echo $?; kill -FOO 1; kill`)
+	if want := "TERM\nKILL\n1\n1\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	want := "kill: %7: no such job\n" +
+		"kill: x: arguments must be process or job IDs\n" +
+		"kill: FOO: invalid signal specification\n" +
+		"kill: missing argument\n"
+	if stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestParseStat(t *testing.T) {
+	stat := "4242 (my (odd) proc) S 1 4242 4242 0 -1 4194560 100 0 0 0 250 130 0 0 20 0 1 0 12345 10000000 300 18446744073709551615"
+	p, err := parseStat(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := process{
+		pid:   4242,
+		ppid:  1,
+		state: "S",
+		rss:   300 * int64(os.Getpagesize()) / 1024,
+		cpu:   3800 * time.Millisecond,
+		comm:  "my (odd) proc",
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("parseStat = %+v, want %+v", p, want)
+	}
+
+	for _, bad := range []string{"", "1 (x) S 1", "x (y) S 1 1 1 0 -1 0 0 0 0 0 1 1 0 0 20 0 1 0 1 1 1"} {
+		if _, err := parseStat(bad); err == nil {
+			t.Errorf("parseStat(%q) expected error", bad)
+		}
+	}
+}
+
+func TestFormatCPU(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "00:00:00"},
+		{d: 3*time.Hour + 4*time.Minute + 5*time.Second + 900*time.Millisecond, want: "03:04:05"},
+		{d: 50 * time.Hour, want: "2-02:00:00"},
+	}
+	for _, test := range tests {
+		if got := formatCPU(test.d); got != test.want {
+			t.Errorf("formatCPU(%v) = %q, want %q", test.d, got, test.want)
+		}
+	}
+}
+
+func TestPs(t *testing.T) {
+	if _, err := os.Stat(filepath.Join(procRoot, "self", "stat")); err != nil {
+		t.Skip("no /proc:", err)
+	}
+	var stdout, stderr bytes.Buffer
+	sh := newShell(strings.NewReader(""), &stdout, &stderr)
+
+	runInput(t, sh, fmt.Sprintf(`ps -o pid,ppid,state,args -p %d`, os.Getpid()))
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != "    PID    PPID S CMD" {
+		t.Fatalf("ps output = %q", stdout.String())
+	}
+	// Состояние тестового процесса зависит от того, какой поток читает /proc
+	want := fmt.Sprintf("%7d %7d S %s", os.Getpid(), os.Getppid(), strings.Join(os.Args, " "))
+	if len(lines[1]) < 17 {
+		t.Fatalf("ps row = %q", lines[1])
+	}
+	if got := lines[1][:16] + "S" + lines[1][17:]; got != want || !strings.ContainsAny(lines[1][16:17], "RS") {
+		t.Errorf("ps row = %q, want %q", lines[1], want)
+	}
+
+	// Все процессы по возрастанию pid; среди них есть сам тест
+	stdout.Reset()
+	runInput(t, sh, `ps -e -o pid`)
+	var pids []int
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n")[1:] {
+		pid, err := strconv.Atoi(strings.TrimSpace(line))
+		if err != nil {
+			t.Fatalf("ps -o pid row %q: %v", line, err)
+		}
+		pids = append(pids, pid)
+	}
+	if !sort.IntsAreSorted(pids) || sort.SearchInts(pids, os.Getpid()) == len(pids) {
+		t.Errorf("ps -o pid = %v", pids)
+	}
+
+	stderr.Reset()
+	runInput(t, sh, `ps -o pid,nope; ps -p x; ps extra; ps -p 999999999; echo $?`)
+	if !strings.Contains(stderr.String(), `ps: unknown column "nope"`) ||
+		!strings.Contains(stderr.String(), `ps: invalid process ID "x"`) ||
+		!strings.Contains(stderr.String(), `ps: unexpected argument "extra"`) {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	if !strings.HasSuffix(stdout.String(), "\n1\n") {
+		t.Errorf("ps -p missing pid: stdout %q", stdout.String())
+	}
+}